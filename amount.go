@@ -0,0 +1,89 @@
+package wxpay
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Fen 金额，单位为分，微信支付V2接口的金额字段均以分为单位的整数传输
+type Fen int64
+
+// MaxFen 单笔金额上限，1亿元
+const MaxFen Fen = 100000000 * 100
+
+var (
+	ErrWxAmountNotPositive = errors.New("wxpay: amount must be positive")
+	ErrWxAmountTooLarge    = fmt.Errorf("wxpay: amount must not exceed %d fen", MaxFen)
+)
+
+// FenFromYuan 将以元为单位的金额字符串转换为分，如 "0.01" 转换为 1，最多两位小数
+func FenFromYuan(yuan string) (Fen, error) {
+	fen, err := parseYuan(yuan)
+	if err != nil {
+		return 0, err
+	}
+	if err = fen.Validate(); err != nil {
+		return 0, err
+	}
+	return fen, nil
+}
+
+// 解析以元为单位的金额，允许0与负数，用于解析账单等微信返回的金额
+func parseYuan(yuan string) (Fen, error) {
+	s := strings.TrimSpace(yuan)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	if s == "" {
+		return 0, fmt.Errorf("wxpay: invalid amount %q", yuan)
+	}
+	integer, fraction, _ := strings.Cut(s, ".")
+	if len(fraction) > 2 {
+		return 0, fmt.Errorf("wxpay: invalid amount %q, at most 2 decimal places", yuan)
+	}
+	fraction += strings.Repeat("0", 2-len(fraction))
+	if integer == "" {
+		integer = "0"
+	}
+	// 仅允许数字，不允许符号与指数
+	for _, r := range integer + fraction {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("wxpay: invalid amount %q", yuan)
+		}
+	}
+	if len(integer) > 16 {
+		return 0, ErrWxAmountTooLarge
+	}
+	y, _ := strconv.ParseInt(integer, 10, 64)
+	f, _ := strconv.ParseInt(fraction, 10, 64)
+	fen := Fen(y*100 + f)
+	if negative {
+		fen = -fen
+	}
+	return fen, nil
+}
+
+// Validate 校验金额，必须大于0且不超过 MaxFen
+func (f Fen) Validate() error {
+	if f <= 0 {
+		return ErrWxAmountNotPositive
+	}
+	if f > MaxFen {
+		return ErrWxAmountTooLarge
+	}
+	return nil
+}
+
+// Yuan 以元为单位的金额字符串，如 1 分返回 "0.01"
+func (f Fen) Yuan() string {
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, f/100, f%100)
+}
+
+func (f Fen) String() string {
+	return strconv.FormatInt(int64(f), 10)
+}
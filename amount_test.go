@@ -0,0 +1,85 @@
+package wxpay
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"testing"
+)
+
+func TestFenFromYuan(t *testing.T) {
+	tests := []struct {
+		yuan string
+		want Fen
+		err  bool
+	}{
+		{"0.01", 1, false},
+		{"1", 100, false},
+		{"1.5", 150, false},
+		{"12.34", 1234, false},
+		{".5", 50, false},
+		{"1000000", 100000000, false},
+		{"0.001", 0, true},
+		{"0", 0, true},
+		{"-1", 0, true},
+		{"1e3", 0, true},
+		{"abc", 0, true},
+		{"", 0, true},
+		{"100000001", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := FenFromYuan(tt.yuan)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("FenFromYuan(%q) = %d, %v, want %d, err %v", tt.yuan, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestFen_Validate(t *testing.T) {
+	if err := Fen(0).Validate(); !errors.Is(err, ErrWxAmountNotPositive) {
+		t.Errorf("Fen(0).Validate() = %v", err)
+	}
+	if err := (MaxFen + 1).Validate(); !errors.Is(err, ErrWxAmountTooLarge) {
+		t.Errorf("(MaxFen+1).Validate() = %v", err)
+	}
+	if err := MaxFen.Validate(); err != nil {
+		t.Errorf("MaxFen.Validate() = %v", err)
+	}
+	if got := Fen(1).Yuan(); got != "0.01" {
+		t.Errorf("Fen(1).Yuan() = %s", got)
+	}
+	if got := Fen(-1050).Yuan(); got != "-10.50" {
+		t.Errorf("Fen(-1050).Yuan() = %s", got)
+	}
+}
+
+func TestFen_Encoding(t *testing.T) {
+	c, err := New(testAppId, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p TradeRefund
+	p.OutRefundNo = "REFUND2023112717521212345678"
+	p.TotalFee = 101
+	p.RefundFee = 1
+	m := c.structToMap(p)
+	if m["total_fee"] != "101" || m["refund_fee"] != "1" || m["out_refund_no"] != p.OutRefundNo {
+		t.Fatalf("structToMap = %v", m)
+	}
+	var rsp TradeRefundRsp
+	if err = xml.Unmarshal([]byte("<xml><refund_fee>1</refund_fee><total_fee>101</total_fee><cash_fee></cash_fee></xml>"), &rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.RefundFee != 1 || rsp.TotalFee != 101 || rsp.CashFee != 0 {
+		t.Fatalf("unexpected xml decode %+v", rsp)
+	}
+	b, err := json.Marshal(struct {
+		TotalFee Fen `json:"total_fee"`
+	}{101})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"total_fee":101}` {
+		t.Fatalf("json = %s", b)
+	}
+}
@@ -1,29 +1,44 @@
 package wxpay
 
 import (
+	"context"
 	"log"
 	"testing"
 )
 
+const (
+	appId  = ""
+	secret = ""
+)
+
 var client *Client
 
 func init() {
+	// 未配置小程序信息时不创建客户端，请求微信接口的测试将被跳过
+	if appId == "" || secret == "" {
+		return
+	}
 	var err error
-	client, err = New("", "")
+	client, err = New(appId, secret, WithMchInformation(mchId, mchSecret), WithReceivedData(func(method string, data []byte) {
+		log.Println(method, string(data))
+	}))
 	if err != nil {
 		log.Fatalln(err)
 	}
-	client.OnReceivedData(func(method string, data []byte) {
-		log.Println(method, string(data))
-	})
+}
+
+func skipWithoutClient(t *testing.T) {
+	if client == nil {
+		t.Skip("appId or secret not configured")
+	}
 }
 
 func TestClient_Code2Session(t *testing.T) {
 	t.Log("========== Code2Session ==========")
-	client.LoadOptionFunc(WithJsCodeHost())
+	skipWithoutClient(t)
 	var p Code2Session
 	p.JsCode = "" // 前端获取的code值
-	r, err := client.Code2Session(p)
+	r, err := client.Code2Session(context.Background(), p)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -32,10 +47,10 @@ func TestClient_Code2Session(t *testing.T) {
 
 func TestClient_GetPhoneNumber(t *testing.T) {
 	t.Log("========== GetPhoneNumber ==========")
-	client.LoadOptionFunc(WithApiHost("https://api.weixin.qq.com/wxa/business/getuserphonenumber?access_token=ACCESS_TOKEN"))
+	skipWithoutClient(t)
 	var p GetPhoneNumber
 	p.Code = "" // 前端获取的code值
-	r, err := client.GetPhoneNumber(p)
+	r, err := client.GetPhoneNumber(context.Background(), p)
 	if err != nil {
 		t.Fatal(err)
 	}
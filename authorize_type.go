@@ -19,6 +19,16 @@ func (a Code2Session) NeedVerify() bool {
 	return false
 }
 
+func (a Code2Session) ApiPath() string {
+	return "/sns/jscode2session"
+}
+
+func (a Code2Session) Validate() error {
+	var v validator
+	v.required("js_code", a.JsCode)
+	return v.err()
+}
+
 // Code2SessionRsp 小程序登录返回参数 https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/user-login/code2Session.html
 type Code2SessionRsp struct {
 	AppletError
@@ -45,6 +55,10 @@ func (a GetAccessToken) NeedVerify() bool {
 	return false
 }
 
+func (a GetAccessToken) ApiPath() string {
+	return "/cgi-bin/token"
+}
+
 // GetAccessTokenRsp 接口调用凭据响应参数
 type GetAccessTokenRsp struct {
 	AppletError
@@ -75,6 +89,20 @@ func (pn GetPhoneNumber) ReturnType() string {
 	return "jsonStr"
 }
 
+func (pn GetPhoneNumber) NeedAccessToken() bool {
+	return true
+}
+
+func (pn GetPhoneNumber) ApiPath() string {
+	return "/wxa/business/getuserphonenumber"
+}
+
+func (pn GetPhoneNumber) Validate() error {
+	var v validator
+	v.required("code", pn.Code)
+	return v.err()
+}
+
 // GetPhoneNumberRsp 获取手机号响应参数
 type GetPhoneNumberRsp struct {
 	AppletError
@@ -0,0 +1,141 @@
+package wxpay
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrWxPublicKeyInvalid = errors.New("wxpay: rsa public key is not a valid PKCS#1 pem")
+
+// GetPublicKey 获取RSA加密公钥 https://pay.weixin.qq.com/wiki/doc/api/tools/mch_pay.php?chapter=24_7&index=4
+// POST https://fraud.mch.weixin.qq.com/risk/getpublickey
+// 需要加载证书，企业付款到银行卡会自动获取并缓存公钥，一般无需直接调用
+func (c *Client) GetPublicKey(ctx context.Context, param GetPublicKey) (result *GetPublicKeyRsp, err error) {
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
+
+// TransferToBankCard 企业付款到银行卡 https://pay.weixin.qq.com/wiki/doc/api/tools/mch_pay.php?chapter=24_2
+// POST https://api.mch.weixin.qq.com/mmpaysptrans/pay_bank
+// 需要加载证书，银行卡号与收款方用户名使用RSA公钥自动加密
+func (c *Client) TransferToBankCard(ctx context.Context, param TransferToBankCard) (result *TransferToBankCardRsp, err error) {
+	if err = param.Validate(); err != nil {
+		return
+	}
+	// 已传入密文的字段不再加密
+	if param.EncBankNo == "" || param.EncTrueName == "" {
+		var key *rsa.PublicKey
+		if key, err = c.rsaPublicKey(ctx); err != nil {
+			return
+		}
+		if param.EncBankNo == "" {
+			if param.EncBankNo, err = rsaEncrypt(key, param.BankNo); err != nil {
+				return
+			}
+		}
+		if param.EncTrueName == "" {
+			if param.EncTrueName, err = rsaEncrypt(key, param.TrueName); err != nil {
+				return
+			}
+		}
+	}
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
+
+// QueryBankTransfer 查询企业付款到银行卡 https://pay.weixin.qq.com/wiki/doc/api/tools/mch_pay.php?chapter=24_3
+// POST https://api.mch.weixin.qq.com/mmpaysptrans/query_bank
+func (c *Client) QueryBankTransfer(ctx context.Context, param QueryBankTransfer) (result *QueryBankTransferRsp, err error) {
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
+
+const kRsaPublicKeyTimeout = 10 * time.Second // 获取RSA公钥的超时时间，获取不受发起调用方取消的影响
+
+// 企业付款到银行卡RSA公钥缓存，公钥长期有效，获取一次后一直使用
+type rsaKeyCache struct {
+	mu   sync.Mutex
+	key  *rsa.PublicKey
+	call *rsaKeyCall
+}
+
+// 正在进行中的获取公钥请求，多个调用方共享同一次获取结果
+type rsaKeyCall struct {
+	done chan struct{}
+	key  *rsa.PublicKey
+	err  error
+}
+
+// 获取RSA公钥，未设置时请求获取RSA公钥接口并缓存，并发获取时只请求一次，各调用方按自己的 ctx 等待
+func (c *Client) rsaPublicKey(ctx context.Context) (*rsa.PublicKey, error) {
+	c.rsaKey.mu.Lock()
+	if key := c.rsaKey.key; key != nil {
+		c.rsaKey.mu.Unlock()
+		return key, nil
+	}
+	call := c.rsaKey.call
+	if call == nil {
+		call = &rsaKeyCall{done: make(chan struct{})}
+		c.rsaKey.call = call
+		go c.fetchRsaPublicKey(context.WithoutCancel(ctx), call)
+	}
+	c.rsaKey.mu.Unlock()
+	select {
+	case <-call.done:
+		return call.key, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// 请求获取RSA公钥接口，ctx 不随发起获取的调用方取消
+func (c *Client) fetchRsaPublicKey(ctx context.Context, call *rsaKeyCall) {
+	ctx, cancel := context.WithTimeout(ctx, kRsaPublicKeyTimeout)
+	defer cancel()
+	rsp, err := c.GetPublicKey(ctx, GetPublicKey{})
+	if err == nil && rsp.ResultCode != string(ReturnCodeSuccess) {
+		err = rsp.PayError
+	}
+	if err == nil {
+		call.key, err = parsePKCS1PublicKey([]byte(rsp.PubKey))
+	}
+	call.err = err
+	c.rsaKey.mu.Lock()
+	if err == nil {
+		c.rsaKey.key = call.key
+	}
+	c.rsaKey.call = nil
+	c.rsaKey.mu.Unlock()
+	close(call.done)
+}
+
+// 解析PKCS#1格式的RSA公钥
+func parsePKCS1PublicKey(pemKey []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, ErrWxPublicKeyInvalid
+	}
+	key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %s", ErrWxPublicKeyInvalid, err.Error())
+	}
+	return key, nil
+}
+
+// RSA-OAEP加密，结果为base64编码
+func rsaEncrypt(key *rsa.PublicKey, plain string) (string, error) {
+	b, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, key, []byte(plain), nil)
+	if err != nil {
+		return "", fmt.Errorf("wxpay: rsa encrypt fail, %s", err.Error())
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,210 @@
+package wxpay
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 模拟企业付款到银行卡接口，使用私钥解密银行卡号与姓名
+func newBankServer(t *testing.T) (server *httptest.Server, pubKey []byte, keyFetches *int32) {
+	t.Helper()
+	signer, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey = pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&priv.PublicKey)})
+	decrypt := func(s string) string {
+		b, _ := base64.StdEncoding.DecodeString(s)
+		plain, _ := rsa.DecryptOAEP(sha1.New(), rand.Reader, priv, b, nil)
+		return string(plain)
+	}
+	write := func(w http.ResponseWriter, m payXml) {
+		b, _ := xml.Marshal(m)
+		w.Write(b)
+	}
+	keyFetches = new(int32)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/risk/getpublickey", func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			write(w, payXml{"return_code": "FAIL", "return_msg": "cert required"})
+			return
+		}
+		if _, err := readTestPayXml(r, signer); err != nil {
+			write(w, payXml{"return_code": "FAIL", "return_msg": err.Error()})
+			return
+		}
+		atomic.AddInt32(keyFetches, 1)
+		write(w, payXml{"return_code": "SUCCESS", "result_code": "SUCCESS", "mch_id": testMchId, "pub_key": string(pubKey)})
+	})
+	mux.HandleFunc("/mmpaysptrans/pay_bank", func(w http.ResponseWriter, r *http.Request) {
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			write(w, payXml{"return_code": "FAIL", "return_msg": err.Error()})
+			return
+		}
+		if m[kFieldAppId] != "" || m[kFieldSignType] != "" || decrypt(m["enc_bank_no"]) != "6222020200000000000" || decrypt(m["enc_true_name"]) != "张三" {
+			write(w, payXml{"return_code": "FAIL", "return_msg": "invalid params"})
+			return
+		}
+		write(w, payXml{"return_code": "SUCCESS", "result_code": "SUCCESS", "partner_trade_no": m["partner_trade_no"], "amount": m["amount"], "payment_no": "10000600500852017030900000020006012", "cmms_amt": "0"})
+	})
+	mux.HandleFunc("/mmpaysptrans/query_bank", func(w http.ResponseWriter, r *http.Request) {
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			write(w, payXml{"return_code": "FAIL", "return_msg": err.Error()})
+			return
+		}
+		write(w, payXml{"return_code": "SUCCESS", "result_code": "SUCCESS", "partner_trade_no": m["partner_trade_no"], "status": BankTransferStatusProcessing, "amount": "500"})
+	})
+	server = httptest.NewUnstartedServer(mux)
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return
+}
+
+func testTransferToBankCard() TransferToBankCard {
+	var p TransferToBankCard
+	p.PartnerTradeNo = "1212121221227"
+	p.BankNo = "6222020200000000000"
+	p.TrueName = "张三"
+	p.BankCode = "1002"
+	p.Amount = 500
+	p.Desc = "理财"
+	return p
+}
+
+func TestClient_TransferToBankCard(t *testing.T) {
+	server, _, keyFetches := newBankServer(t)
+	pemCert, keyCert := newTestCert(t)
+	c := newTestClient(t, server, WithTlsCert(pemCert, keyCert))
+	for i := 0; i < 2; i++ {
+		r, err := c.TransferToBankCard(context.Background(), testTransferToBankCard())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.PaymentNo == "" || r.Amount != 500 {
+			t.Fatalf("unexpected result %+v", r)
+		}
+	}
+	// 公钥只获取一次
+	if n := atomic.LoadInt32(keyFetches); n != 1 {
+		t.Fatalf("expected 1 public key fetch, got %d", n)
+	}
+	q, err := c.QueryBankTransfer(context.Background(), QueryBankTransfer{PartnerTradeNo: "1212121221227"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Status != BankTransferStatusProcessing || q.Amount != 500 {
+		t.Fatalf("unexpected query result %+v", q)
+	}
+}
+
+func TestClient_TransferToBankCardPublicKey(t *testing.T) {
+	server, pubKey, keyFetches := newBankServer(t)
+	pemCert, keyCert := newTestCert(t)
+	c := newTestClient(t, server, WithTlsCert(pemCert, keyCert), WithRSAPublicKey(pubKey))
+	if _, err := c.TransferToBankCard(context.Background(), testTransferToBankCard()); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(keyFetches); n != 0 {
+		t.Fatalf("expected no public key fetch, got %d", n)
+	}
+	// 只传入收款方用户名密文时，仅加密银行卡号
+	key, err := parsePKCS1PublicKey(pubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := testTransferToBankCard()
+	p.TrueName = ""
+	if p.EncTrueName, err = rsaEncrypt(key, "张三"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.TransferToBankCard(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(testAppId, testSecret, WithRSAPublicKey([]byte("invalid"))); !errors.Is(err, ErrWxPublicKeyInvalid) {
+		t.Fatalf("expected ErrWxPublicKeyInvalid, got %v", err)
+	}
+}
+
+// 获取公钥期间等待的调用方按自己的 ctx 返回，获取结果由所有调用方共享
+func TestClient_RsaPublicKeyWaitContext(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&priv.PublicKey)})
+	release := make(chan struct{})
+	var fetches int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		b, _ := xml.Marshal(payXml{"return_code": "SUCCESS", "result_code": "SUCCESS", "mch_id": testMchId, "pub_key": string(pubKey)})
+		w.Write(b)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	pemCert, keyCert := newTestCert(t)
+	c := newTestClient(t, server, WithTlsCert(pemCert, keyCert))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.rsaPublicKey(context.Background())
+		done <- err
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = c.rsaPublicKey(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	close(release)
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+	if key, err := c.rsaPublicKey(ctx); err != nil || key.N.Cmp(priv.N) != 0 {
+		t.Fatalf("expected cached key, got %v", err)
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Fatalf("expected 1 public key fetch, got %d", n)
+	}
+}
+
+func TestClient_GetPublicKeyUrl(t *testing.T) {
+	c, err := New(testAppId, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := c.requestUrl(GetPublicKey{}); u != "https://fraud.mch.weixin.qq.com/risk/getpublickey" {
+		t.Fatalf("unexpected url %s", u)
+	}
+}
+
+func TestTransferToBankCard_Validate(t *testing.T) {
+	p := testTransferToBankCard()
+	p.PartnerTradeNo = "1212121"
+	p.BankNo = ""
+	p.TrueName = ""
+	fields := invalidFields(t, p.Validate())
+	if len(fields) != 3 || fields[0] != "partner_trade_no" || fields[1] != "bank_no" || fields[2] != "true_name" {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+}
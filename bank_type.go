@@ -0,0 +1,178 @@
+package wxpay
+
+// 企业付款到银行卡状态
+const (
+	BankTransferStatusProcessing = "PROCESSING" // 处理中，如有明确失败，则返回额外失败原因；否则没有错误原因
+	BankTransferStatusSuccess    = "SUCCESS"    // 付款成功
+	BankTransferStatusFailed     = "FAILED"     // 付款失败，需要替换付款单号重新发起付款
+	BankTransferStatusBankFail   = "BANK_FAIL"  // 银行退票，订单状态由付款成功流转至退票，退票时付款金额和手续费会自动退还
+)
+
+/* 获取RSA加密公钥 */
+
+// GetPublicKey 获取RSA加密公钥 https://pay.weixin.qq.com/wiki/doc/api/tools/mch_pay.php?chapter=24_7&index=4
+type GetPublicKey struct {
+	AuxParam
+}
+
+func (g GetPublicKey) NeedAppId() bool {
+	return false
+}
+
+func (g GetPublicKey) NeedVerify() bool {
+	return false
+}
+
+func (g GetPublicKey) NeedTlsCert() bool {
+	return true
+}
+
+func (g GetPublicKey) ReturnType() string {
+	return "xml"
+}
+
+func (g GetPublicKey) ApiPath() string {
+	return "https://fraud.mch.weixin.qq.com/risk/getpublickey"
+}
+
+// 获取RSA加密公钥只支持MD5签名
+func (g GetPublicKey) SignType() string {
+	return SignTypeMD5
+}
+
+// GetPublicKeyRsp 获取RSA加密公钥响应参数
+type GetPublicKeyRsp struct {
+	PayError
+	MchId  string `xml:"mch_id" json:"mch_id"`   // 微信支付分配的商户号
+	PubKey string `xml:"pub_key" json:"pub_key"` // RSA公钥，PKCS#1格式
+}
+
+/* 企业付款到银行卡 */
+
+// TransferToBankCard 企业付款到银行卡 https://pay.weixin.qq.com/wiki/doc/api/tools/mch_pay.php?chapter=24_2
+type TransferToBankCard struct {
+	AuxParam
+	PartnerTradeNo string `xml:"partner_trade_no" json:"partner_trade_no"` // 商户订单号，需保持唯一（只允许数字[0~9]或字母[A~Z]和[a~z]，最短8位，最长32位）
+	BankNo         string `xml:"-" json:"-"`                               // 收款方银行卡号，明文，请求时自动使用RSA公钥加密为 enc_bank_no
+	TrueName       string `xml:"-" json:"-"`                               // 收款方用户名，明文，请求时自动使用RSA公钥加密为 enc_true_name
+	EncBankNo      string `xml:"enc_bank_no" json:"enc_bank_no"`           // 收款方银行卡号（采用标准RSA算法，公钥由微信侧提供），自动填充
+	EncTrueName    string `xml:"enc_true_name" json:"enc_true_name"`       // 收款方用户名（采用标准RSA算法，公钥由微信侧提供），自动填充
+	BankCode       string `xml:"bank_code" json:"bank_code"`               // 银行卡所在开户行编号，如：1002 工商银行
+	Amount         Fen    `xml:"amount" json:"amount"`                     // 付款金额：RMB分（支付总额，不含手续费）
+	Desc           string `xml:"desc,omitempty" json:"desc,omitempty"`     // 企业付款到银行卡付款说明，即订单备注（UTF8编码，允许100个字符以内）
+}
+
+func (t TransferToBankCard) NeedAppId() bool {
+	return false
+}
+
+func (t TransferToBankCard) NeedVerify() bool {
+	return false
+}
+
+func (t TransferToBankCard) NeedTlsCert() bool {
+	return true
+}
+
+func (t TransferToBankCard) ReturnType() string {
+	return "xml"
+}
+
+func (t TransferToBankCard) ApiPath() string {
+	return "/mmpaysptrans/pay_bank"
+}
+
+// 企业付款到银行卡只支持MD5签名，且不传签名类型
+func (t TransferToBankCard) SignType() string {
+	return SignTypeMD5
+}
+
+func (t TransferToBankCard) Fields() ParamFields {
+	return ParamFields{MchId: kFieldMchId}
+}
+
+func (t TransferToBankCard) Validate() error {
+	var v validator
+	v.bankPartnerTradeNo(t.PartnerTradeNo)
+	if t.EncBankNo == "" {
+		v.required("bank_no", t.BankNo)
+	}
+	if t.EncTrueName == "" {
+		v.required("true_name", t.TrueName)
+	}
+	v.required("bank_code", t.BankCode)
+	v.amount("amount", t.Amount)
+	v.maxLen("desc", t.Desc, 100)
+	return v.err()
+}
+
+// TransferToBankCardRsp 企业付款到银行卡响应参数，返回成功仅表示受理成功，付款结果需通过 QueryBankTransfer 查询
+type TransferToBankCardRsp struct {
+	PayError
+	MchId          string `xml:"mch_id" json:"mch_id"`                     // 微信支付分配的商户号
+	PartnerTradeNo string `xml:"partner_trade_no" json:"partner_trade_no"` // 商户订单号
+	Amount         Fen    `xml:"amount" json:"amount"`                     // 代付金额，RMB分
+	NonceStr       string `xml:"nonce_str" json:"nonce_str"`               // 随机字符串，长度小于32位
+	Sign           string `xml:"sign" json:"sign"`                         // 返回包携带签名给商户
+	PaymentNo      string `xml:"payment_no" json:"payment_no"`             // 代付成功后，返回的内部业务单号
+	CmmsAmt        Fen    `xml:"cmms_amt" json:"cmms_amt"`                 // 手续费金额，RMB分
+}
+
+/* 查询企业付款到银行卡 */
+
+// QueryBankTransfer 查询企业付款到银行卡 https://pay.weixin.qq.com/wiki/doc/api/tools/mch_pay.php?chapter=24_3
+type QueryBankTransfer struct {
+	AuxParam
+	PartnerTradeNo string `xml:"partner_trade_no" json:"partner_trade_no"` // 商户订单号，需保持唯一（只允许数字[0~9]或字母[A~Z]和[a~z]最短8位，最长32位）
+}
+
+func (q QueryBankTransfer) NeedAppId() bool {
+	return false
+}
+
+func (q QueryBankTransfer) NeedVerify() bool {
+	return false
+}
+
+func (q QueryBankTransfer) NeedTlsCert() bool {
+	return true
+}
+
+func (q QueryBankTransfer) ReturnType() string {
+	return "xml"
+}
+
+func (q QueryBankTransfer) ApiPath() string {
+	return "/mmpaysptrans/query_bank"
+}
+
+// 查询企业付款到银行卡只支持MD5签名，且不传签名类型
+func (q QueryBankTransfer) SignType() string {
+	return SignTypeMD5
+}
+
+func (q QueryBankTransfer) Fields() ParamFields {
+	return ParamFields{MchId: kFieldMchId}
+}
+
+func (q QueryBankTransfer) Validate() error {
+	var v validator
+	v.bankPartnerTradeNo(q.PartnerTradeNo)
+	return v.err()
+}
+
+// QueryBankTransferRsp 查询企业付款到银行卡响应参数
+type QueryBankTransferRsp struct {
+	PayError
+	MchId          string `xml:"mch_id" json:"mch_id"`                     // 商户号
+	PartnerTradeNo string `xml:"partner_trade_no" json:"partner_trade_no"` // 商户单号
+	PaymentNo      string `xml:"payment_no" json:"payment_no"`             // 微信企业付款单号
+	BankNoMd5      string `xml:"bank_no_md5" json:"bank_no_md5"`           // 收款用户银行卡号(MD5加密)
+	TrueNameMd5    string `xml:"true_name_md5" json:"true_name_md5"`       // 收款人真实姓名（MD5加密）
+	Amount         Fen    `xml:"amount" json:"amount"`                     // 代付订单金额RMB：分
+	Status         string `xml:"status" json:"status"`                     // 代付订单状态：PROCESSING、SUCCESS、FAILED、BANK_FAIL
+	CmmsAmt        Fen    `xml:"cmms_amt" json:"cmms_amt"`                 // 手续费订单金额 RMB：分
+	CreateTime     string `xml:"create_time" json:"create_time"`           // 微信侧订单创建时间
+	PaySuccTime    string `xml:"pay_succ_time" json:"pay_succ_time"`       // 微信侧付款成功时间（依赖银行的处理进度，可能出现延迟返回，甚至被银行退票的情况）
+	Reason         string `xml:"reason" json:"reason"`                     // 订单失败原因（如：余额不足）
+}
@@ -0,0 +1,351 @@
+package wxpay
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	kBillTimeFormat    = "2006-01-02 15:04:05" // 账单时间格式
+	kBillMaxLineSize   = 1 << 20               // 账单单行最大长度
+	kBillErrorPeekSize = 5                     // 识别xml错误信息时读取的长度
+)
+
+// 账单时间为北京时间
+var billLocation = time.FixedZone("CST", 8*60*60)
+
+// DownloadBill 下载交易账单 https://pay.weixin.qq.com/wiki/doc/api/jsapi.php?chapter=9_6
+// POST https://api.mch.weixin.qq.com/pay/downloadbill
+// 账单明细全部加载到内存，账单较大时使用 DownloadBillReader
+func (c *Client) DownloadBill(ctx context.Context, param DownloadBill) (result *Bill, err error) {
+	r, err := c.DownloadBillReader(ctx, param)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+	return readBill(r)
+}
+
+// DownloadBillReader 下载交易账单，返回的 BillReader 边下载边逐行解析，内存占用与账单大小无关，使用完后需要调用 Close
+func (c *Client) DownloadBillReader(ctx context.Context, param DownloadBill) (result *BillReader, err error) {
+	body, err := c.download(ctx, "POST", param)
+	if err != nil {
+		return
+	}
+	result = NewBillReader(body)
+	result.closer = body
+	return
+}
+
+// ParseBill 解析交易账单，r 为微信返回的账单文本（已解压），可用于解析已保存的账单文件
+func ParseBill(r io.Reader) (*Bill, error) {
+	return readBill(NewBillReader(r))
+}
+
+func readBill(r *BillReader) (*Bill, error) {
+	bill := new(Bill)
+	for r.Next() {
+		bill.Records = append(bill.Records, r.Record())
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	bill.Summary = r.Summary()
+	return bill, nil
+}
+
+// BillReader 交易账单读取器，逐行解析账单明细
+//
+//	for r.Next() {
+//		record := r.Record()
+//	}
+//	if err := r.Err(); err != nil {
+//	}
+//	summary := r.Summary()
+type BillReader struct {
+	scanner *billScanner
+	closer  io.Closer
+	record  BillRecord
+	summary BillSummary
+	err     error
+}
+
+// NewBillReader 创建交易账单读取器，r 为交易账单文本（已解压），可用于解析已保存的账单文件
+func NewBillReader(r io.Reader) *BillReader {
+	return &BillReader{scanner: newBillScanner(r)}
+}
+
+// Next 读取下一条明细，没有更多明细或出错时返回 false
+func (r *BillReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if !r.scanner.next() {
+		if r.err = r.scanner.err; r.err == nil {
+			r.summary, r.err = newBillSummary(r.scanner.summaryRow())
+		}
+		return false
+	}
+	r.record, r.err = newBillRecord(r.scanner.row())
+	return r.err == nil
+}
+
+// Record 当前明细
+func (r *BillReader) Record() BillRecord {
+	return r.record
+}
+
+// Err 读取过程中的错误
+func (r *BillReader) Err() error {
+	return r.err
+}
+
+// Summary 账单汇总，Next 返回 false 后可用
+func (r *BillReader) Summary() BillSummary {
+	return r.summary
+}
+
+// Close 关闭下载的账单
+func (r *BillReader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// 下载账单类文件，返回解压后的账单内容，返回xml格式的错误信息时解析为 PayError
+func (c *Client) download(ctx context.Context, method string, param Param) (body io.ReadCloser, err error) {
+	if err = param.Validate(); err != nil {
+		return
+	}
+	rsp, err := c.send(ctx, method, param, "")
+	if err != nil {
+		return
+	}
+	if rsp.StatusCode != http.StatusOK {
+		rsp.Body.Close()
+		return nil, fmt.Errorf("%w, %s", ErrWxHttpStatus, rsp.Status)
+	}
+	br := bufio.NewReader(rsp.Body)
+	head, _ := br.Peek(kBillErrorPeekSize)
+	// 失败时返回xml格式的错误信息
+	if bytes.HasPrefix(head, []byte("<xml")) {
+		defer rsp.Body.Close()
+		var data []byte
+		if data, err = io.ReadAll(br); err != nil {
+			return
+		}
+		if c.onReceivedData != nil {
+			c.onReceivedData(method, data)
+		}
+		var pErr PayError
+		if err = xml.Unmarshal(data, &pErr); err != nil {
+			return
+		}
+		if pErr.IsFailure() {
+			return nil, pErr
+		}
+		return nil, fmt.Errorf("wxpay: unexpected download response, %s", pErr.ReturnMsg)
+	}
+	// gzip格式压缩的账单
+	if len(head) >= 2 && head[0] == 0x1f && head[1] == 0x8b {
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(br); err != nil {
+			rsp.Body.Close()
+			return nil, fmt.Errorf("wxpay: read gzip bill fail, %s", err.Error())
+		}
+		return &downloadBody{Reader: zr, closers: []io.Closer{zr, rsp.Body}}, nil
+	}
+	return &downloadBody{Reader: br, closers: []io.Closer{rsp.Body}}, nil
+}
+
+// 下载的账单内容，关闭时同时关闭解压器与响应
+type downloadBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *downloadBody) Close() (err error) {
+	for _, closer := range b.closers {
+		if cErr := closer.Close(); err == nil {
+			err = cErr
+		}
+	}
+	return
+}
+
+// 账单文本按行读取，依次为表头、数据行、汇总表头、汇总行，数据行与汇总行的每个字段以`开头
+type billScanner struct {
+	scanner       *bufio.Scanner
+	header        map[string]int
+	fields        []string
+	summaryHeader map[string]int
+	summary       []string
+	err           error
+}
+
+func newBillScanner(r io.Reader) *billScanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), kBillMaxLineSize)
+	return &billScanner{scanner: scanner}
+}
+
+// 读取下一条数据行，数据行读取完后继续读取汇总
+func (s *billScanner) next() bool {
+	if s.err != nil {
+		return false
+	}
+	for s.scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(s.scanner.Text(), "\ufeff"))
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "`") {
+			header := make(map[string]int)
+			for i, name := range strings.Split(line, ",") {
+				header[strings.TrimSpace(name)] = i
+			}
+			if s.header == nil {
+				s.header = header
+			} else {
+				s.summaryHeader = header
+			}
+			continue
+		}
+		fields := strings.Split(line[1:], ",`")
+		if s.header == nil {
+			s.err = fmt.Errorf("wxpay: bill header not found")
+			return false
+		}
+		if s.summaryHeader != nil {
+			s.summary = fields
+			continue
+		}
+		s.fields = fields
+		return true
+	}
+	s.err = s.scanner.Err()
+	if s.err == nil && s.header == nil {
+		s.err = fmt.Errorf("wxpay: bill header not found")
+	}
+	return false
+}
+
+// 当前数据行
+func (s *billScanner) row() *billRow {
+	return &billRow{header: s.header, fields: s.fields}
+}
+
+// 汇总行，账单不包含汇总时各字段为零值
+func (s *billScanner) summaryRow() *billRow {
+	return &billRow{header: s.summaryHeader, fields: s.summary}
+}
+
+// 账单行，按表头名称取值，同一字段在不同版本账单中的表头名称可能不同
+type billRow struct {
+	header map[string]int
+	fields []string
+	err    error
+}
+
+func (r *billRow) str(names ...string) string {
+	for _, name := range names {
+		if i, ok := r.header[name]; ok && i < len(r.fields) {
+			return strings.TrimSpace(r.fields[i])
+		}
+	}
+	return ""
+}
+
+func (r *billRow) fen(names ...string) Fen {
+	value := r.str(names...)
+	if value == "" || r.err != nil {
+		return 0
+	}
+	fen, err := parseYuan(value)
+	if err != nil {
+		r.err = fmt.Errorf("wxpay: parse bill field %s fail, %s", names[0], err.Error())
+	}
+	return fen
+}
+
+func (r *billRow) int(names ...string) int {
+	value := r.str(names...)
+	if value == "" || r.err != nil {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		r.err = fmt.Errorf("wxpay: parse bill field %s fail, %s", names[0], err.Error())
+	}
+	return n
+}
+
+func (r *billRow) time(names ...string) time.Time {
+	value := r.str(names...)
+	if value == "" || r.err != nil {
+		return time.Time{}
+	}
+	t, err := time.ParseInLocation(kBillTimeFormat, value, billLocation)
+	if err != nil {
+		r.err = fmt.Errorf("wxpay: parse bill field %s fail, %s", names[0], err.Error())
+	}
+	return t
+}
+
+func newBillRecord(r *billRow) (BillRecord, error) {
+	record := BillRecord{
+		TradeTime:           r.time("交易时间"),
+		AppId:               r.str("公众账号ID"),
+		MchId:               r.str("商户号"),
+		SubMchId:            r.str("特约商户号", "子商户号"),
+		DeviceInfo:          r.str("设备号"),
+		TransactionId:       r.str("微信订单号"),
+		OutTradeNo:          r.str("商户订单号"),
+		OpenId:              r.str("用户标识"),
+		TradeType:           r.str("交易类型"),
+		TradeState:          r.str("交易状态"),
+		BankType:            r.str("付款银行"),
+		FeeType:             r.str("货币种类"),
+		SettlementTotalFee:  r.fen("应结订单金额", "总金额"),
+		CouponFee:           r.fen("代金券金额", "代金券或立减优惠金额", "企业红包金额"),
+		RefundApplyTime:     r.time("退款申请时间"),
+		RefundSuccessTime:   r.time("退款成功时间"),
+		RefundId:            r.str("微信退款单号"),
+		OutRefundNo:         r.str("商户退款单号"),
+		SettlementRefundFee: r.fen("退款金额"),
+		CouponRefundFee:     r.fen("充值券退款金额", "代金券或立减优惠退款金额", "企业红包退款金额"),
+		RefundType:          r.str("退款类型"),
+		RefundStatus:        r.str("退款状态"),
+		Body:                r.str("商品名称"),
+		Attach:              r.str("商户数据包"),
+		ServiceCharge:       r.str("手续费"),
+		Rate:                r.str("费率"),
+		TotalFee:            r.fen("订单金额"),
+		RefundFee:           r.fen("申请退款金额"),
+		RateRemark:          r.str("费率备注"),
+	}
+	return record, r.err
+}
+
+func newBillSummary(r *billRow) (BillSummary, error) {
+	summary := BillSummary{
+		TotalCount:          r.int("总交易单数"),
+		SettlementTotalFee:  r.fen("应结订单总金额", "总交易额"),
+		SettlementRefundFee: r.fen("退款总金额", "总退款金额"),
+		CouponRefundFee:     r.fen("充值券退款总金额", "总代金券或立减优惠退款金额", "总企业红包退款金额"),
+		ServiceCharge:       r.str("手续费总金额"),
+		TotalFee:            r.fen("订单总金额"),
+		RefundFee:           r.fen("申请退款总金额"),
+	}
+	return summary, r.err
+}
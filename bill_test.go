@@ -0,0 +1,177 @@
+package wxpay
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBillAll = "\ufeff交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,微信退款单号,商户退款单号,退款金额,充值券退款金额,退款类型,退款状态,商品名称,商户数据包,手续费,费率,订单金额,申请退款金额,费率备注\r\n" +
+	"`2014-11-10 16:33:45,`wx2421b1c4370ec43b,`10000100,`0,`1000,`1001690740201411100005734289,`1415640626,`085e9858e3ba5186aafcbaed1,`MICROPAY,`SUCCESS,`OTHERS,`CNY,`0.01,`0.00,`0,`0,`0.00,`0.00,`,`,`被扫支付测试,`订单额外描述,`0.00000,`0.60%,`0.01,`0.00,`\r\n" +
+	"`2014-11-10 16:46:14,`wx2421b1c4370ec43b,`10000100,`0,`1000,`1002780740201411100005729794,`1415635270,`085e9858e90ca40c0b5aee463,`MICROPAY,`REFUND,`OTHERS,`CNY,`0.01,`0.00,`2000000000000000001,`R1415635270,`0.01,`0.00,`ORIGINAL,`SUCCESS,`被扫支付测试,`订单额外描述,`0.00000,`0.60%,`0.01,`0.01,`\r\n" +
+	"总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\r\n" +
+	"`2,`0.02,`0.01,`0.00,`0.00000,`0.02,`0.01\r\n"
+
+// 模拟下载账单接口，tar_type=GZIP时返回gzip压缩的账单
+func newBillServer(t *testing.T, bill string) *httptest.Server {
+	t.Helper()
+	signer, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			w.Write([]byte("<xml><return_code><![CDATA[FAIL]]></return_code><return_msg><![CDATA[" + err.Error() + "]]></return_msg></xml>"))
+			return
+		}
+		if m["bill_date"] == "20141109" {
+			w.Write([]byte("<xml><return_code><![CDATA[FAIL]]></return_code><return_msg><![CDATA[No Bill Exist]]></return_msg><error_code><![CDATA[20002]]></error_code></xml>"))
+			return
+		}
+		if m["bill_date"] == "20141108" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if m["tar_type"] == TarTypeGzip {
+			zw := gzip.NewWriter(w)
+			zw.Write([]byte(bill))
+			zw.Close()
+			return
+		}
+		w.Write([]byte(bill))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestParseBill(t *testing.T) {
+	bill, err := ParseBill(strings.NewReader(testBillAll))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bill.Records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(bill.Records))
+	}
+	r := bill.Records[1]
+	if r.OutTradeNo != "1415635270" || r.TradeState != "REFUND" || r.OutRefundNo != "R1415635270" || r.Rate != "0.60%" {
+		t.Fatalf("unexpected record %+v", r)
+	}
+	if r.TotalFee != 1 || r.SettlementRefundFee != 1 || r.RefundFee != 1 || r.CouponFee != 0 {
+		t.Fatalf("unexpected amounts %+v", r)
+	}
+	if want := time.Date(2014, 11, 10, 16, 46, 14, 0, billLocation); !r.TradeTime.Equal(want) {
+		t.Fatalf("unexpected trade time %s", r.TradeTime)
+	}
+	want := BillSummary{TotalCount: 2, SettlementTotalFee: 2, SettlementRefundFee: 1, ServiceCharge: "0.00000", TotalFee: 2, RefundFee: 1}
+	if bill.Summary != want {
+		t.Fatalf("unexpected summary %+v", bill.Summary)
+	}
+}
+
+func TestParseBill_Invalid(t *testing.T) {
+	if _, err := ParseBill(strings.NewReader("`2014-11-10 16:33:45,`wx2421b1c4370ec43b\r\n")); err == nil {
+		t.Fatal("expected header not found error")
+	}
+	bad := strings.Replace(testBillAll, "`0.01,`0.00,`0,`0", "`abc,`0.00,`0,`0", 1)
+	if _, err := ParseBill(strings.NewReader(bad)); err == nil {
+		t.Fatal("expected amount error")
+	}
+}
+
+func TestClient_DownloadBill(t *testing.T) {
+	server := newBillServer(t, testBillAll)
+	c := newTestClient(t, server)
+	for _, tarType := range []string{"", TarTypeGzip} {
+		bill, err := c.DownloadBill(context.Background(), DownloadBill{BillDate: "20141110", BillType: BillTypeAll, TarType: tarType})
+		if err != nil {
+			t.Fatal(tarType, err)
+		}
+		if len(bill.Records) != 2 || bill.Summary.TotalCount != 2 {
+			t.Fatalf("%s: unexpected bill %+v", tarType, bill)
+		}
+	}
+}
+
+func TestClient_DownloadBillError(t *testing.T) {
+	var received []byte
+	server := newBillServer(t, testBillAll)
+	c := newTestClient(t, server, WithReceivedData(func(method string, data []byte) {
+		received = data
+	}))
+	_, err := c.DownloadBill(context.Background(), DownloadBill{BillDate: "20141109", BillType: BillTypeAll, TarType: TarTypeGzip})
+	var pErr PayError
+	if !errors.As(err, &pErr) || pErr.ReturnMsg != "No Bill Exist" {
+		t.Fatalf("expected No Bill Exist, got %v", err)
+	}
+	if !bytes.Contains(received, []byte("No Bill Exist")) {
+		t.Fatalf("expected error envelope passed to received data, got %s", received)
+	}
+	if _, err = c.DownloadBill(context.Background(), DownloadBill{BillDate: "20141108", BillType: BillTypeAll}); !errors.Is(err, ErrWxHttpStatus) {
+		t.Fatalf("expected ErrWxHttpStatus, got %v", err)
+	}
+	if _, err = c.DownloadBill(context.Background(), DownloadBill{BillDate: "2014-11-10"}); err == nil {
+		t.Fatal("expected validation error")
+	}
+}
+
+func TestBillReader_Streaming(t *testing.T) {
+	lines := strings.SplitAfter(testBillAll, "\r\n")
+	pr, pw := io.Pipe()
+	written := make(chan int, len(lines))
+	go func() {
+		for i, line := range lines {
+			pw.Write([]byte(line))
+			written <- i
+		}
+		pw.Close()
+	}()
+	r := NewBillReader(pr)
+	// 读取到第一条明细时后续内容尚未写入
+	if !r.Next() {
+		t.Fatal(r.Err())
+	}
+	if r.Record().OutTradeNo != "1415640626" {
+		t.Fatalf("unexpected record %+v", r.Record())
+	}
+	if n := len(written); n > 2 {
+		t.Fatalf("expected incremental parsing, %d lines written", n)
+	}
+	count := 1
+	for r.Next() {
+		count++
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || r.Summary().TotalCount != 2 {
+		t.Fatalf("unexpected count %d, summary %+v", count, r.Summary())
+	}
+}
+
+func TestClient_DownloadBillReader(t *testing.T) {
+	server := newBillServer(t, testBillAll)
+	c := newTestClient(t, server)
+	r, err := c.DownloadBillReader(context.Background(), DownloadBill{BillDate: "20141110", BillType: BillTypeAll, TarType: TarTypeGzip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var fee Fen
+	for r.Next() {
+		fee += r.Record().TotalFee
+	}
+	if err = r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if fee != r.Summary().TotalFee {
+		t.Fatalf("expected total fee %d, got %d", r.Summary().TotalFee, fee)
+	}
+}
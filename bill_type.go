@@ -0,0 +1,101 @@
+package wxpay
+
+import "time"
+
+// 账单类型
+const (
+	BillTypeAll            = "ALL"             // 返回当日所有订单信息（不含充值退款订单）
+	BillTypeSuccess        = "SUCCESS"         // 返回当日成功支付的订单（不含充值退款订单）
+	BillTypeRefund         = "REFUND"          // 返回当日退款订单（不含充值退款订单）
+	BillTypeRechargeRefund = "RECHARGE_REFUND" // 返回当日充值退款订单
+)
+
+// TarTypeGzip 账单压缩类型，返回gzip格式压缩的账单
+const TarTypeGzip = "GZIP"
+
+/* 下载交易账单 */
+
+// DownloadBill 下载交易账单 https://pay.weixin.qq.com/wiki/doc/api/jsapi.php?chapter=9_6
+type DownloadBill struct {
+	AuxParam
+	BillDate string `xml:"bill_date" json:"bill_date"`                   // 下载对账单的日期，格式：20140603
+	BillType string `xml:"bill_type" json:"bill_type"`                   // ALL（默认值），返回当日所有订单信息（不含充值退款订单）SUCCESS，返回当日成功支付的订单（不含充值退款订单）REFUND，返回当日退款订单（不含充值退款订单）RECHARGE_REFUND，返回当日充值退款订单
+	TarType  string `xml:"tar_type,omitempty" json:"tar_type,omitempty"` // 非必传参数，固定值：GZIP，返回格式为.gzip的压缩包账单。不传则默认为数据流形式。
+}
+
+// 账单返回文本格式，不返回签名
+func (d DownloadBill) NeedVerify() bool {
+	return false
+}
+
+func (d DownloadBill) ReturnType() string {
+	return "xml"
+}
+
+func (d DownloadBill) ApiPath() string {
+	return "/pay/downloadbill"
+}
+
+func (d DownloadBill) Validate() error {
+	var v validator
+	v.billDate(d.BillDate)
+	switch d.BillType {
+	case "", BillTypeAll, BillTypeSuccess, BillTypeRefund, BillTypeRechargeRefund:
+	default:
+		v.add("bill_type", "must be one of ALL, SUCCESS, REFUND, RECHARGE_REFUND")
+	}
+	if d.TarType != "" && d.TarType != TarTypeGzip {
+		v.add("tar_type", "must be GZIP or empty")
+	}
+	return v.err()
+}
+
+// Bill 交易账单
+type Bill struct {
+	Records []BillRecord // 账单明细
+	Summary BillSummary  // 账单汇总
+}
+
+// BillRecord 交易账单明细，不同账单类型返回的字段不同，未返回的字段为零值
+type BillRecord struct {
+	TradeTime           time.Time // 交易时间
+	AppId               string    // 公众账号ID
+	MchId               string    // 商户号
+	SubMchId            string    // 特约商户号
+	DeviceInfo          string    // 设备号
+	TransactionId       string    // 微信订单号
+	OutTradeNo          string    // 商户订单号
+	OpenId              string    // 用户标识
+	TradeType           string    // 交易类型
+	TradeState          string    // 交易状态
+	BankType            string    // 付款银行
+	FeeType             string    // 货币种类
+	SettlementTotalFee  Fen       // 应结订单金额
+	CouponFee           Fen       // 代金券金额
+	RefundApplyTime     time.Time // 退款申请时间，REFUND、RECHARGE_REFUND账单返回
+	RefundSuccessTime   time.Time // 退款成功时间，REFUND、RECHARGE_REFUND账单返回
+	RefundId            string    // 微信退款单号
+	OutRefundNo         string    // 商户退款单号
+	SettlementRefundFee Fen       // 退款金额
+	CouponRefundFee     Fen       // 充值券退款金额
+	RefundType          string    // 退款类型
+	RefundStatus        string    // 退款状态
+	Body                string    // 商品名称
+	Attach              string    // 商户数据包
+	ServiceCharge       string    // 手续费，单位为元，保留5位小数，如 0.00600
+	Rate                string    // 费率，如 0.60%
+	TotalFee            Fen       // 订单金额
+	RefundFee           Fen       // 申请退款金额
+	RateRemark          string    // 费率备注
+}
+
+// BillSummary 交易账单汇总
+type BillSummary struct {
+	TotalCount          int    // 总交易单数
+	SettlementTotalFee  Fen    // 应结订单总金额
+	SettlementRefundFee Fen    // 退款总金额
+	CouponRefundFee     Fen    // 充值券退款总金额
+	ServiceCharge       string // 手续费总金额，单位为元，保留5位小数
+	TotalFee            Fen    // 订单总金额
+	RefundFee           Fen    // 申请退款总金额
+}
@@ -0,0 +1,34 @@
+package wxpay
+
+import "context"
+
+// SendCoupon 发放代金券 https://pay.weixin.qq.com/wiki/doc/api/tools/sp_coupon.php?chapter=12_3&index=4
+// POST https://api.mch.weixin.qq.com/mmpaymkttransfers/send_coupon
+// 需要加载证书，openid_count 为空时自动填充为1，相同 partner_trade_no 重入时不会重复发券，
+// 业务结果成功但发放失败（ret_code 为 FAILED）时同时返回结果与 PayError，ErrCode 为 FAILED，ErrCodeDes 为 ret_msg
+func (c *Client) SendCoupon(ctx context.Context, param SendCoupon) (result *SendCouponRsp, err error) {
+	if param.OpenIdCount == 0 {
+		param.OpenIdCount = 1
+	}
+	if err = c.doRequest(ctx, "POST", param, &result); err != nil {
+		return
+	}
+	if result.ResultCode == string(ReturnCodeSuccess) && result.RetCode == kCouponRetCodeFailed {
+		err = PayError{ReturnCode: result.ReturnCode, ReturnMsg: result.ReturnMsg, ErrCode: result.RetCode, ErrCodeDes: result.RetMsg, ResultCode: result.ResultCode}
+	}
+	return
+}
+
+// QueryCouponStock 查询代金券批次 https://pay.weixin.qq.com/wiki/doc/api/tools/sp_coupon.php?chapter=12_4&index=5
+// POST https://api.mch.weixin.qq.com/mmpaymkttransfers/query_coupon_stock
+func (c *Client) QueryCouponStock(ctx context.Context, param QueryCouponStock) (result *QueryCouponStockRsp, err error) {
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
+
+// QueryCouponsInfo 查询代金券信息 https://pay.weixin.qq.com/wiki/doc/api/tools/sp_coupon.php?chapter=12_5&index=6
+// POST https://api.mch.weixin.qq.com/mmpaymkttransfers/querycouponsinfo
+func (c *Client) QueryCouponsInfo(ctx context.Context, param QueryCouponsInfo) (result *QueryCouponsInfoRsp, err error) {
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
@@ -0,0 +1,153 @@
+package wxpay
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// 模拟代金券接口，发券要求客户端证书，校验不带 sign_type 的MD5签名，返回数据同样使用MD5签名
+func newCouponServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	signer, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(w http.ResponseWriter, m payXml) {
+		writeTestPayXml(w, signer, m, SignTypeMD5)
+	}
+	read := func(w http.ResponseWriter, r *http.Request) (payXml, bool) {
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			write(w, payXml{"return_code": "FAIL", "return_msg": err.Error()})
+			return nil, false
+		}
+		if m[kFieldAppId] != testAppId || m[kFieldMchId] != testMchId || m[kFieldSignType] != "" {
+			write(w, payXml{"return_code": "FAIL", "return_msg": "invalid fields"})
+			return nil, false
+		}
+		return m, true
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mmpaymkttransfers/send_coupon", func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			write(w, payXml{"return_code": "FAIL", "return_msg": "cert required"})
+			return
+		}
+		m, ok := read(w, r)
+		if !ok {
+			return
+		}
+		if m["openid_count"] != "1" {
+			write(w, payXml{"return_code": "FAIL", "return_msg": "invalid openid_count"})
+			return
+		}
+		if m["coupon_stock_id"] == "1758" {
+			write(w, payXml{"return_code": "SUCCESS", "result_code": "SUCCESS", "coupon_stock_id": m["coupon_stock_id"], "resp_count": "1", "success_count": "0", "failed_count": "1", "openid": m["openid"], "ret_code": "FAILED", "ret_msg": "批次已发放完"})
+			return
+		}
+		write(w, payXml{
+			"return_code":     "SUCCESS",
+			"result_code":     "SUCCESS",
+			"coupon_stock_id": m["coupon_stock_id"],
+			"resp_count":      "1",
+			"success_count":   "1",
+			"failed_count":    "0",
+			"openid":          m["openid"],
+			"ret_code":        "SUCCESS",
+			"coupon_id":       "1870",
+		})
+	})
+	mux.HandleFunc("/mmpaymkttransfers/query_coupon_stock", func(w http.ResponseWriter, r *http.Request) {
+		m, ok := read(w, r)
+		if !ok {
+			return
+		}
+		write(w, payXml{
+			"return_code":         "SUCCESS",
+			"result_code":         "SUCCESS",
+			"coupon_stock_id":     m["coupon_stock_id"],
+			"coupon_value":        "500",
+			"coupon_mininumn":     "1000",
+			"coupon_stock_status": "4",
+			"coupon_total":        "100",
+			"is_send_num":         "1",
+		})
+	})
+	mux.HandleFunc("/mmpaymkttransfers/querycouponsinfo", func(w http.ResponseWriter, r *http.Request) {
+		m, ok := read(w, r)
+		if !ok {
+			return
+		}
+		write(w, payXml{
+			"return_code":     "SUCCESS",
+			"result_code":     "SUCCESS",
+			"coupon_stock_id": m["stock_id"],
+			"coupon_id":       m["coupon_id"],
+			"coupon_value":    "500",
+			"coupon_mininum":  "1000",
+			"coupon_state":    CouponStateSended,
+		})
+	})
+	server := httptest.NewUnstartedServer(mux)
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_Coupon(t *testing.T) {
+	server := newCouponServer(t)
+	pemCert, keyCert := newTestCert(t)
+	c := newTestClient(t, server, WithTlsCert(pemCert, keyCert), WithSignType(SignTypeHMACSHA256))
+
+	r, err := c.SendCoupon(context.Background(), SendCoupon{
+		CouponStockId:  "1757",
+		PartnerTradeNo: testMchId + "201411111234567890",
+		OpenId:         "oxTWIuGaIt6gTKsQRLau2M0yL16E",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RetCode != "SUCCESS" || r.CouponId != "1870" || r.SuccessCount != 1 {
+		t.Fatalf("unexpected result %+v", r)
+	}
+
+	stock, err := c.QueryCouponStock(context.Background(), QueryCouponStock{CouponStockId: "1757"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stock.CouponStockStatus != CouponStockStatusActivated || stock.CouponValue != 500 || stock.CouponMinimum != 1000 {
+		t.Fatalf("unexpected stock %+v", stock)
+	}
+
+	info, err := c.QueryCouponsInfo(context.Background(), QueryCouponsInfo{CouponId: r.CouponId, OpenId: r.OpenId, StockId: "1757"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CouponState != CouponStateSended || info.CouponStockId != "1757" || info.CouponMinimum != 1000 {
+		t.Fatalf("unexpected coupon info %+v", info)
+	}
+}
+
+// 发放失败时返回 PayError，结果中保留 ret_msg
+func TestClient_SendCouponFail(t *testing.T) {
+	server := newCouponServer(t)
+	pemCert, keyCert := newTestCert(t)
+	c := newTestClient(t, server, WithTlsCert(pemCert, keyCert))
+	r, err := c.SendCoupon(context.Background(), SendCoupon{
+		CouponStockId:  "1758",
+		PartnerTradeNo: testMchId + "201411111234567891",
+		OpenId:         "oxTWIuGaIt6gTKsQRLau2M0yL16E",
+	})
+	var pErr PayError
+	if !errors.As(err, &pErr) || pErr.ErrCode != "FAILED" || pErr.ErrCodeDes != "批次已发放完" {
+		t.Fatalf("expected FAILED, got %v", err)
+	}
+	if r == nil || r.FailedCount != 1 {
+		t.Fatalf("unexpected result %+v", r)
+	}
+}
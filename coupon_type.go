@@ -0,0 +1,208 @@
+package wxpay
+
+// 代金券批次状态
+const (
+	CouponStockStatusInactive  = 1  // 未激活
+	CouponStockStatusAuditing  = 2  // 审批中
+	CouponStockStatusActivated = 4  // 已激活
+	CouponStockStatusCanceled  = 8  // 已作废
+	CouponStockStatusSuspended = 16 // 中止发放
+)
+
+// 代金券状态
+const (
+	CouponStateSended  = "SENDED"  // 可用
+	CouponStateUsed    = "USED"    // 已实扣
+	CouponStateExpired = "EXPIRED" // 已过期
+)
+
+const kCouponRetCodeFailed = "FAILED" // 发放代金券失败的返回码
+
+/* 发放代金券 */
+
+// SendCoupon 发放代金券 https://pay.weixin.qq.com/wiki/doc/api/tools/sp_coupon.php?chapter=12_3&index=4
+type SendCoupon struct {
+	AuxParam
+	CouponStockId  string `xml:"coupon_stock_id" json:"coupon_stock_id"`             // 代金券批次id
+	OpenIdCount    int    `xml:"openid_count" json:"openid_count"`                   // openid记录数（目前支持num=1），为空时自动填充
+	PartnerTradeNo string `xml:"partner_trade_no" json:"partner_trade_no"`           // 商户此次发放凭据号（格式：商户id+日期+流水号），商户侧需保持唯一性
+	OpenId         string `xml:"openid" json:"openid"`                               // 用户在商户appid下的唯一标识
+	OpUserId       string `xml:"op_user_id,omitempty" json:"op_user_id,omitempty"`   // 操作员帐号，默认为商户号
+	DeviceInfo     string `xml:"device_info,omitempty" json:"device_info,omitempty"` // 微信支付分配的终端设备号
+	Version        string `xml:"version,omitempty" json:"version,omitempty"`         // 协议版本，默认1.0
+	Type           string `xml:"type,omitempty" json:"type,omitempty"`               // 协议类型，XML【目前仅支持默认XML】
+}
+
+func (s SendCoupon) NeedTlsCert() bool {
+	return true
+}
+
+func (s SendCoupon) ReturnType() string {
+	return "xml"
+}
+
+func (s SendCoupon) ApiPath() string {
+	return "/mmpaymkttransfers/send_coupon"
+}
+
+// 代金券接口只支持MD5签名，且不传签名类型
+func (s SendCoupon) SignType() string {
+	return SignTypeMD5
+}
+
+func (s SendCoupon) Fields() ParamFields {
+	return ParamFields{AppId: kFieldAppId, MchId: kFieldMchId}
+}
+
+func (s SendCoupon) Validate() error {
+	var v validator
+	v.required("coupon_stock_id", s.CouponStockId)
+	if s.OpenIdCount != 1 {
+		v.add("openid_count", "must be 1")
+	}
+	if v.required("partner_trade_no", s.PartnerTradeNo) {
+		v.maxLen("partner_trade_no", s.PartnerTradeNo, 50)
+	}
+	v.required("openid", s.OpenId)
+	return v.err()
+}
+
+// SendCouponRsp 发放代金券响应参数，ret_code 为 SUCCESS 时发放成功，为 FAILED 时 SendCoupon 返回 PayError
+type SendCouponRsp struct {
+	PayError
+	AppId         string `xml:"appid" json:"appid"`                     // 微信为发券方商户分配的公众账号ID
+	MchId         string `xml:"mch_id" json:"mch_id"`                   // 微信为发券方商户分配的商户号
+	DeviceInfo    string `xml:"device_info" json:"device_info"`         // 微信支付分配的终端设备号
+	NonceStr      string `xml:"nonce_str" json:"nonce_str"`             // 随机字符串，不长于32位
+	Sign          string `xml:"sign" json:"sign"`                       // 签名
+	CouponStockId string `xml:"coupon_stock_id" json:"coupon_stock_id"` // 用户在商户appid下领取的代金券批次id
+	RespCount     int    `xml:"resp_count" json:"resp_count"`           // 返回记录数
+	SuccessCount  int    `xml:"success_count" json:"success_count"`     // 成功记录数
+	FailedCount   int    `xml:"failed_count" json:"failed_count"`       // 失败记录数
+	OpenId        string `xml:"openid" json:"openid"`                   // 用户在商户appid下的唯一标识
+	RetCode       string `xml:"ret_code" json:"ret_code"`               // 返回码，SUCCESS/FAILED
+	CouponId      string `xml:"coupon_id" json:"coupon_id"`             // 对一个用户成功发放代金券则返回代金券id，即coupon_id，如果失败则为空
+	RetMsg        string `xml:"ret_msg" json:"ret_msg"`                 // 返回信息，当返回码是FAILED的时候填写，否则填空串""
+}
+
+/* 查询代金券批次 */
+
+// QueryCouponStock 查询代金券批次 https://pay.weixin.qq.com/wiki/doc/api/tools/sp_coupon.php?chapter=12_4&index=5
+type QueryCouponStock struct {
+	AuxParam
+	CouponStockId string `xml:"coupon_stock_id" json:"coupon_stock_id"`             // 代金券批次id
+	OpUserId      string `xml:"op_user_id,omitempty" json:"op_user_id,omitempty"`   // 操作员帐号，默认为商户号
+	DeviceInfo    string `xml:"device_info,omitempty" json:"device_info,omitempty"` // 微信支付分配的终端设备号
+	Version       string `xml:"version,omitempty" json:"version,omitempty"`         // 协议版本，默认1.0
+	Type          string `xml:"type,omitempty" json:"type,omitempty"`               // 协议类型，XML【目前仅支持默认XML】
+}
+
+func (q QueryCouponStock) ReturnType() string {
+	return "xml"
+}
+
+func (q QueryCouponStock) ApiPath() string {
+	return "/mmpaymkttransfers/query_coupon_stock"
+}
+
+// 代金券接口只支持MD5签名，且不传签名类型
+func (q QueryCouponStock) SignType() string {
+	return SignTypeMD5
+}
+
+func (q QueryCouponStock) Fields() ParamFields {
+	return ParamFields{AppId: kFieldAppId, MchId: kFieldMchId}
+}
+
+func (q QueryCouponStock) Validate() error {
+	var v validator
+	v.required("coupon_stock_id", q.CouponStockId)
+	return v.err()
+}
+
+// QueryCouponStockRsp 查询代金券批次响应参数
+type QueryCouponStockRsp struct {
+	PayError
+	AppId             string `xml:"appid" json:"appid"`                             // 公众账号ID
+	MchId             string `xml:"mch_id" json:"mch_id"`                           // 商户号
+	DeviceInfo        string `xml:"device_info" json:"device_info"`                 // 微信支付分配的终端设备号
+	CouponStockId     string `xml:"coupon_stock_id" json:"coupon_stock_id"`         // 代金券批次ID
+	CouponName        string `xml:"coupon_name" json:"coupon_name"`                 // 代金券名称
+	CouponValue       Fen    `xml:"coupon_value" json:"coupon_value"`               // 代金券面额，单位分
+	CouponMinimum     Fen    `xml:"coupon_mininumn" json:"coupon_mininumn"`         // 代金券使用最低限额，单位分
+	CouponStockStatus int    `xml:"coupon_stock_status" json:"coupon_stock_status"` // 批次状态：1-未激活；2-审批中；4-已激活；8-已作废；16-中止发放
+	CouponTotal       int    `xml:"coupon_total" json:"coupon_total"`               // 代金券数量
+	MaxQuota          int    `xml:"max_quota" json:"max_quota"`                     // 代金券每个人最多能领取的数量, 如果为0，则表示没有限制
+	IsSendNum         int    `xml:"is_send_num" json:"is_send_num"`                 // 代金券已经发送的数量
+	BeginTime         string `xml:"begin_time" json:"begin_time"`                   // 批次生效开始时间，格式为yyyyMMddhhmmss
+	EndTime           string `xml:"end_time" json:"end_time"`                       // 批次生效结束时间，格式为yyyyMMddhhmmss
+	CreateTime        string `xml:"create_time" json:"create_time"`                 // 批次创建时间，格式为yyyyMMddhhmmss
+	CouponBudget      Fen    `xml:"coupon_budget" json:"coupon_budget"`             // 代金券预算额度，单位分
+}
+
+/* 查询代金券信息 */
+
+// QueryCouponsInfo 查询代金券信息 https://pay.weixin.qq.com/wiki/doc/api/tools/sp_coupon.php?chapter=12_5&index=6
+type QueryCouponsInfo struct {
+	AuxParam
+	CouponId   string `xml:"coupon_id" json:"coupon_id"`                         // 代金券id
+	OpenId     string `xml:"openid" json:"openid"`                               // 用户在商户appid下的唯一标识
+	StockId    string `xml:"stock_id" json:"stock_id"`                           // 代金劵对应的批次号
+	OpUserId   string `xml:"op_user_id,omitempty" json:"op_user_id,omitempty"`   // 操作员帐号，默认为商户号
+	DeviceInfo string `xml:"device_info,omitempty" json:"device_info,omitempty"` // 微信支付分配的终端设备号
+	Version    string `xml:"version,omitempty" json:"version,omitempty"`         // 协议版本，默认1.0
+	Type       string `xml:"type,omitempty" json:"type,omitempty"`               // 协议类型，XML【目前仅支持默认XML】
+}
+
+func (q QueryCouponsInfo) ReturnType() string {
+	return "xml"
+}
+
+func (q QueryCouponsInfo) ApiPath() string {
+	return "/mmpaymkttransfers/querycouponsinfo"
+}
+
+// 代金券接口只支持MD5签名，且不传签名类型
+func (q QueryCouponsInfo) SignType() string {
+	return SignTypeMD5
+}
+
+func (q QueryCouponsInfo) Fields() ParamFields {
+	return ParamFields{AppId: kFieldAppId, MchId: kFieldMchId}
+}
+
+func (q QueryCouponsInfo) Validate() error {
+	var v validator
+	v.required("coupon_id", q.CouponId)
+	v.required("openid", q.OpenId)
+	v.required("stock_id", q.StockId)
+	return v.err()
+}
+
+// QueryCouponsInfoRsp 查询代金券信息响应参数
+type QueryCouponsInfoRsp struct {
+	PayError
+	AppId             string `xml:"appid" json:"appid"`                             // 公众账号ID
+	MchId             string `xml:"mch_id" json:"mch_id"`                           // 商户号
+	DeviceInfo        string `xml:"device_info" json:"device_info"`                 // 微信支付分配的终端设备号
+	CouponStockId     string `xml:"coupon_stock_id" json:"coupon_stock_id"`         // 代金券批次ID
+	CouponStockType   int    `xml:"coupon_stock_type" json:"coupon_stock_type"`     // 批次类型；1-批量型，2-触发型
+	CouponId          string `xml:"coupon_id" json:"coupon_id"`                     // 代金券id
+	CouponValue       Fen    `xml:"coupon_value" json:"coupon_value"`               // 代金券面值，单位分
+	CouponMinimum     Fen    `xml:"coupon_mininum" json:"coupon_mininum"`           // 代金券使用最低限额，单位分
+	CouponName        string `xml:"coupon_name" json:"coupon_name"`                 // 代金券名称
+	CouponState       string `xml:"coupon_state" json:"coupon_state"`               // 代金券状态：SENDED-可用，USED-已实扣，EXPIRED-已过期
+	CouponDesc        string `xml:"coupon_desc" json:"coupon_desc"`                 // 代金券描述
+	CouponUseValue    Fen    `xml:"coupon_use_value" json:"coupon_use_value"`       // 代金券实际使用金额，单位分
+	CouponRemainValue Fen    `xml:"coupon_remain_value" json:"coupon_remain_value"` // 代金券剩余金额，部分使用情况下，可能会存在券剩余金额
+	BeginTime         string `xml:"begin_time" json:"begin_time"`                   // 生效开始时间，格式为yyyyMMddhhmmss
+	EndTime           string `xml:"end_time" json:"end_time"`                       // 生效结束时间，格式为yyyyMMddhhmmss
+	SendTime          string `xml:"send_time" json:"send_time"`                     // 发放时间，格式为yyyyMMddhhmmss
+	UseTime           string `xml:"use_time" json:"use_time"`                       // 使用时间，格式为yyyyMMddhhmmss
+	TradeNo           string `xml:"trade_no" json:"trade_no"`                       // 使用单号，代金券使用后对应的订单号
+	ConsumerMchId     string `xml:"consumer_mch_id" json:"consumer_mch_id"`         // 消耗方商户id
+	ConsumerMchName   string `xml:"consumer_mch_name" json:"consumer_mch_name"`     // 消耗方商户名称
+	ConsumerMchAppId  string `xml:"consumer_mch_appid" json:"consumer_mch_appid"`   // 消耗方商户appid
+	SendSource        string `xml:"send_source" json:"send_source"`                 // 发放来源：FULL_SEND-满送，NORMAL-普通发劵场景
+	IsPartialUse      string `xml:"is_partial_use" json:"is_partial_use"`           // 该代金券是否允许部分使用标识：1-表示支持部分使用
+}
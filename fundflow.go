@@ -0,0 +1,106 @@
+package wxpay
+
+import (
+	"context"
+	"io"
+)
+
+// DownloadFundFlow 下载资金账单 https://pay.weixin.qq.com/wiki/doc/api/jsapi.php?chapter=9_18&index=7
+// POST https://api.mch.weixin.qq.com/pay/downloadfundflow
+// 需要加载证书，固定使用 HMAC-SHA256 签名，返回的 FundFlowReader 按行读取账单，使用完后需要调用 Close
+func (c *Client) DownloadFundFlow(ctx context.Context, param DownloadFundFlow) (result *FundFlowReader, err error) {
+	body, err := c.download(ctx, "POST", param)
+	if err != nil {
+		return
+	}
+	result = NewFundFlowReader(body)
+	result.closer = body
+	return
+}
+
+// FundFlowReader 资金账单读取器，逐行解析账单明细
+//
+//	for r.Next() {
+//		record := r.Record()
+//	}
+//	if err := r.Err(); err != nil {
+//	}
+//	summary := r.Summary()
+type FundFlowReader struct {
+	scanner *billScanner
+	closer  io.Closer
+	record  FundFlowRecord
+	summary FundFlowSummary
+	err     error
+}
+
+// NewFundFlowReader 创建资金账单读取器，r 为资金账单文本（已解压），可用于解析已保存的账单文件
+func NewFundFlowReader(r io.Reader) *FundFlowReader {
+	return &FundFlowReader{scanner: newBillScanner(r)}
+}
+
+// Next 读取下一条明细，没有更多明细或出错时返回 false
+func (r *FundFlowReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if !r.scanner.next() {
+		if r.err = r.scanner.err; r.err == nil {
+			r.summary, r.err = newFundFlowSummary(r.scanner.summaryRow())
+		}
+		return false
+	}
+	r.record, r.err = newFundFlowRecord(r.scanner.row())
+	return r.err == nil
+}
+
+// Record 当前明细
+func (r *FundFlowReader) Record() FundFlowRecord {
+	return r.record
+}
+
+// Err 读取过程中的错误
+func (r *FundFlowReader) Err() error {
+	return r.err
+}
+
+// Summary 账单汇总，Next 返回 false 后可用
+func (r *FundFlowReader) Summary() FundFlowSummary {
+	return r.summary
+}
+
+// Close 关闭下载的账单
+func (r *FundFlowReader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+func newFundFlowRecord(r *billRow) (FundFlowRecord, error) {
+	record := FundFlowRecord{
+		AccountingTime: r.time("记账时间"),
+		TransactionId:  r.str("微信支付业务单号"),
+		FlowId:         r.str("资金流水单号"),
+		BizName:        r.str("业务名称"),
+		BizType:        r.str("业务类型"),
+		FinancialType:  r.str("收支类型"),
+		Amount:         r.fen("收支金额（元）", "收支金额(元)", "收支金额"),
+		Balance:        r.fen("账户结余（元）", "账户结余(元)", "账户结余"),
+		Applicant:      r.str("资金变更提交申请人"),
+		Remark:         r.str("备注"),
+		BizVoucherId:   r.str("业务凭证号"),
+	}
+	return record, r.err
+}
+
+func newFundFlowSummary(r *billRow) (FundFlowSummary, error) {
+	summary := FundFlowSummary{
+		TotalCount:    r.int("资金流水总笔数"),
+		IncomeCount:   r.int("收入笔数"),
+		IncomeAmount:  r.fen("收入金额"),
+		ExpenseCount:  r.int("支出笔数"),
+		ExpenseAmount: r.fen("支出金额"),
+	}
+	return summary, r.err
+}
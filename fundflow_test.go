@@ -0,0 +1,88 @@
+package wxpay
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testFundFlow = "记账时间,微信支付业务单号,资金流水单号,业务名称,业务类型,收支类型,收支金额（元）,账户结余（元）,资金变更提交申请人,备注,业务凭证号\r\n" +
+	"`2018-02-01 04:21:23,`42000000792018013189651523,`4200000079201801318965152320180201,`退款,`退款,`支出,`0.02,`0.17,`system,`缺货,`REF4200000079201801318965152320180201\r\n" +
+	"`2018-02-01 04:21:24,`42000000792018013189651524,`4200000079201801318965152420180201,`交易,`交易,`收入,`0.19,`0.36,`system,`,`4200000079201801318965152420180201\r\n" +
+	"资金流水总笔数,收入笔数,收入金额,支出笔数,支出金额\r\n" +
+	"`2,`1,`0.19,`1,`0.02\r\n"
+
+// 模拟下载资金账单接口，要求客户端证书与 HMAC-SHA256 签名
+func newFundFlowServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	signer, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			w.Write([]byte("<xml><return_code><![CDATA[FAIL]]></return_code><return_msg><![CDATA[cert required]]></return_msg></xml>"))
+			return
+		}
+		m, err := readTestPayXml(r, signer)
+		if err != nil || m[kFieldSignType] != SignTypeHMACSHA256 {
+			w.Write([]byte("<xml><return_code><![CDATA[FAIL]]></return_code><return_msg><![CDATA[sign_type must be HMAC-SHA256]]></return_msg></xml>"))
+			return
+		}
+		w.Write([]byte(testFundFlow))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_DownloadFundFlow(t *testing.T) {
+	server := newFundFlowServer(t)
+	pemCert, keyCert := newTestCert(t)
+	c := newTestClient(t, server, WithTlsCert(pemCert, keyCert))
+	r, err := c.DownloadFundFlow(context.Background(), DownloadFundFlow{BillDate: "20180201", AccountType: AccountTypeBasic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var records []FundFlowRecord
+	for r.Next() {
+		records = append(records, r.Record())
+	}
+	if err = r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if rec := records[0]; rec.FinancialType != "支出" || rec.Amount != 2 || rec.Balance != 17 || rec.Remark != "缺货" {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+	want := FundFlowSummary{TotalCount: 2, IncomeCount: 1, IncomeAmount: 19, ExpenseCount: 1, ExpenseAmount: 2}
+	if r.Summary() != want {
+		t.Fatalf("unexpected summary %+v", r.Summary())
+	}
+}
+
+func TestClient_DownloadFundFlowTlsCertRequired(t *testing.T) {
+	server := newFundFlowServer(t)
+	c := newTestClient(t, server)
+	_, err := c.DownloadFundFlow(context.Background(), DownloadFundFlow{BillDate: "20180201", AccountType: AccountTypeBasic})
+	if !errors.Is(err, ErrWxPemKeyNotFound) {
+		t.Fatalf("expected ErrWxPemKeyNotFound, got %v", err)
+	}
+}
+
+func TestFundFlowReader_Invalid(t *testing.T) {
+	r := NewFundFlowReader(strings.NewReader(strings.Replace(testFundFlow, "`0.02,`0.17", "`0.02,`abc", 1)))
+	for r.Next() {
+	}
+	if r.Err() == nil {
+		t.Fatal("expected amount error")
+	}
+}
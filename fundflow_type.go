@@ -0,0 +1,80 @@
+package wxpay
+
+import "time"
+
+// 资金账户类型
+const (
+	AccountTypeBasic     = "Basic"     // 基本账户
+	AccountTypeOperation = "Operation" // 运营账户
+	AccountTypeFees      = "Fees"      // 手续费账户
+)
+
+/* 下载资金账单 */
+
+// DownloadFundFlow 下载资金账单 https://pay.weixin.qq.com/wiki/doc/api/jsapi.php?chapter=9_18&index=7
+type DownloadFundFlow struct {
+	AuxParam
+	BillDate    string `xml:"bill_date" json:"bill_date"`                   // 下载对账单的日期，格式：20140603
+	AccountType string `xml:"account_type" json:"account_type"`             // 账单的资金来源账户：Basic 基本账户 Operation 运营账户 Fees 手续费账户
+	TarType     string `xml:"tar_type,omitempty" json:"tar_type,omitempty"` // 非必传参数，固定值：GZIP，返回格式为.gzip的压缩包账单。不传则默认为数据流形式。
+}
+
+// 资金账单返回文本格式，不返回签名
+func (d DownloadFundFlow) NeedVerify() bool {
+	return false
+}
+
+func (d DownloadFundFlow) NeedTlsCert() bool {
+	return true
+}
+
+func (d DownloadFundFlow) ReturnType() string {
+	return "xml"
+}
+
+func (d DownloadFundFlow) ApiPath() string {
+	return "/pay/downloadfundflow"
+}
+
+// 下载资金账单只支持 HMAC-SHA256 签名
+func (d DownloadFundFlow) SignType() string {
+	return SignTypeHMACSHA256
+}
+
+func (d DownloadFundFlow) Validate() error {
+	var v validator
+	v.billDate(d.BillDate)
+	switch d.AccountType {
+	case AccountTypeBasic, AccountTypeOperation, AccountTypeFees:
+	default:
+		v.add("account_type", "must be one of Basic, Operation, Fees")
+	}
+	if d.TarType != "" && d.TarType != TarTypeGzip {
+		v.add("tar_type", "must be GZIP or empty")
+	}
+	return v.err()
+}
+
+// FundFlowRecord 资金账单明细
+type FundFlowRecord struct {
+	AccountingTime time.Time // 记账时间
+	TransactionId  string    // 微信支付业务单号
+	FlowId         string    // 资金流水单号
+	BizName        string    // 业务名称
+	BizType        string    // 业务类型，如：交易、退款、提现
+	FinancialType  string    // 收支类型，收入、支出
+	Amount         Fen       // 收支金额
+	Balance        Fen       // 账户结余
+	Applicant      string    // 资金变更提交申请人
+	Remark         string    // 备注
+	BizVoucherId   string    // 业务凭证号
+}
+
+// FundFlowSummary 资金账单汇总
+type FundFlowSummary struct {
+	TotalCount    int // 资金流水总笔数
+	IncomeCount   int // 收入笔数
+	IncomeAmount  Fen // 收入金额
+	ExpenseCount  int // 支出笔数
+	ExpenseAmount Fen // 支出金额
+}
@@ -0,0 +1,111 @@
+package wxpay
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrWxMicropayReversed 付款码支付未在限定时间内完成或支付失败，订单已撤销
+	ErrWxMicropayReversed = errors.New("wxpay: micropay not completed, order reversed")
+	// ErrWxReverseRecall 多次撤销订单后仍返回需要继续撤销
+	ErrWxReverseRecall = errors.New("wxpay: reverse not completed, recall required")
+)
+
+const (
+	kMicropayWait         = 30 * time.Second // 付款码支付等待用户支付的最长时间
+	kMicropayPollInterval = 5 * time.Second  // 付款码支付查询订单的间隔
+	kReverseMaxAttempts   = 3                // 撤销订单最多调用次数
+	kReverseTimeout       = 30 * time.Second // 调用方取消或超时后撤销订单的超时时间
+)
+
+// TradeMicropayAndWait 付款码支付并等待支付结果，按微信推荐流程处理 https://pay.weixin.qq.com/wiki/doc/api/micropay.php?chapter=5_4&index=3
+//
+// 返回 USERPAYING、SYSTEMERROR、BANKERROR 或请求超时时，轮询查询订单直到支付成功，
+// 超过 WithMicropayPolling 设置的等待时间仍未支付或支付失败时撤销订单并返回 ErrWxMicropayReversed，撤销订单需要加载证书，
+// ctx 取消或超时时同样撤销订单，撤销不受 ctx 影响；未加载证书时不发起支付，直接返回 ErrWxPemKeyNotFound
+func (c *Client) TradeMicropayAndWait(ctx context.Context, param TradeMicropay) (result *TradeOrderQueryRsp, err error) {
+	// 撤销订单需要证书，未加载时用户扣款后无法撤销
+	if c.tlsClient == nil {
+		return nil, ErrWxPemKeyNotFound
+	}
+	rsp, err := c.TradeMicropay(ctx, param)
+	if err != nil {
+		// 明确的失败直接返回，网络错误、ctx 结束等结果未知时需查询或撤销订单
+		var pErr PayError
+		var vErr ValidationErrors
+		if errors.As(err, &pErr) || errors.As(err, &vErr) {
+			return nil, err
+		}
+	} else if rsp.ResultCode == string(ReturnCodeSuccess) {
+		return rsp.orderQueryRsp(), nil
+	} else if !micropayPending(rsp.ErrCode) {
+		return nil, rsp.PayError
+	}
+	deadline := time.Now().Add(c.micropayWait)
+poll:
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			break poll
+		case <-time.After(c.micropayPoll):
+		}
+		query, qErr := c.TradeOrderQuery(ctx, TradeOrderQuery{SubMerchant: param.SubMerchant, OutTradeNo: param.OutTradeNo})
+		if qErr != nil || query.ResultCode != string(ReturnCodeSuccess) {
+			continue
+		}
+		if query.TradeState == TradeStateSuccess {
+			return query, nil
+		}
+		if query.TradeState != TradeStateUserPaying && query.TradeState != TradeStateNotPay {
+			break
+		}
+	}
+	// 调用方取消后用户仍可能完成支付，撤销订单使用不随 ctx 取消的上下文
+	rCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), kReverseTimeout)
+	defer cancel()
+	// 撤销前再查询一次，避免撤销在最后一次查询后完成支付的订单
+	query, qErr := c.TradeOrderQuery(rCtx, TradeOrderQuery{SubMerchant: param.SubMerchant, OutTradeNo: param.OutTradeNo})
+	if qErr == nil && query.ResultCode == string(ReturnCodeSuccess) && query.TradeState == TradeStateSuccess {
+		return query, nil
+	}
+	if err = c.TradeReverseUntilDone(rCtx, TradeReverse{SubMerchant: param.SubMerchant, OutTradeNo: param.OutTradeNo}); err != nil {
+		return nil, err
+	}
+	return nil, ErrWxMicropayReversed
+}
+
+// TradeReverseUntilDone 撤销订单，返回 recall 为 Y 时按查询间隔重试，最多调用3次，仍未完成时返回 ErrWxReverseRecall，
+// 设置了重试策略时网络错误等可重试的错误同样在这3次内重试，每次调用不再按重试策略单独重试
+func (c *Client) TradeReverseUntilDone(ctx context.Context, param TradeReverse) error {
+	param.untilDone = true
+	for i := 0; i < kReverseMaxAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.micropayPoll):
+			}
+		}
+		rsp, err := c.TradeReverse(ctx, param)
+		if err != nil {
+			if i+1 < kReverseMaxAttempts && c.retry.shouldRetry(ctx, 1, err, "") {
+				continue
+			}
+			return err
+		}
+		if !rsp.NeedRecall() {
+			if rsp.ResultCode != string(ReturnCodeSuccess) {
+				return rsp.PayError
+			}
+			return nil
+		}
+	}
+	return ErrWxReverseRecall
+}
+
+// 付款码支付结果是否未知，需要查询订单确认
+func micropayPending(errCode string) bool {
+	return errCode == PayErrCodeUserPaying || errCode == PayErrCodeSystemError || errCode == PayErrCodeBankError
+}
@@ -0,0 +1,256 @@
+package wxpay
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 模拟付款码支付，用户在第 paidAfter 次查询时完成支付，小于等于0时始终未支付，前 recalls 次撤销返回需要继续撤销
+func newMicropayServer(t *testing.T, paidAfter, recalls int32) (server *httptest.Server, queries, reverses *int32) {
+	t.Helper()
+	signer, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries, reverses = new(int32), new(int32)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pay/micropay", func(w http.ResponseWriter, r *http.Request) {
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			writeTestPayXml(w, signer, map[string]string{"return_code": "FAIL", "return_msg": err.Error()})
+			return
+		}
+		if m["auth_code"] == "134567890123456789" {
+			writeTestPayXml(w, signer, map[string]string{"return_code": "SUCCESS", "result_code": "FAIL", "err_code": "AUTHCODEEXPIRE", "err_code_des": "二维码已过期"})
+			return
+		}
+		writeTestPayXml(w, signer, map[string]string{"return_code": "SUCCESS", "result_code": "FAIL", "err_code": PayErrCodeUserPaying, "err_code_des": "需要用户输入支付密码"})
+	})
+	mux.HandleFunc("/pay/orderquery", func(w http.ResponseWriter, r *http.Request) {
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			writeTestPayXml(w, signer, map[string]string{"return_code": "FAIL", "return_msg": err.Error()})
+			return
+		}
+		state := TradeStateUserPaying
+		if n := atomic.AddInt32(queries, 1); paidAfter > 0 && n >= paidAfter {
+			state = TradeStateSuccess
+		}
+		writeTestPayXml(w, signer, map[string]string{
+			"return_code":    "SUCCESS",
+			"result_code":    "SUCCESS",
+			"trade_state":    state,
+			"out_trade_no":   m["out_trade_no"],
+			"transaction_id": "4200" + m["out_trade_no"],
+			"total_fee":      "1",
+		})
+	})
+	mux.HandleFunc("/secapi/pay/reverse", func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			writeTestPayXml(w, signer, map[string]string{"return_code": "FAIL", "return_msg": "cert required"})
+			return
+		}
+		if _, err := readTestPayXml(r, signer); err != nil {
+			writeTestPayXml(w, signer, map[string]string{"return_code": "FAIL", "return_msg": err.Error()})
+			return
+		}
+		if atomic.AddInt32(reverses, 1) <= recalls {
+			writeTestPayXml(w, signer, map[string]string{"return_code": "SUCCESS", "result_code": "FAIL", "err_code": PayErrCodeSystemError, "recall": "Y"})
+			return
+		}
+		writeTestPayXml(w, signer, map[string]string{"return_code": "SUCCESS", "result_code": "SUCCESS", "recall": "N"})
+	})
+	server = httptest.NewUnstartedServer(mux)
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return
+}
+
+func newMicropayClient(t *testing.T, server *httptest.Server) *Client {
+	pemCert, keyCert := newTestCert(t)
+	return newTestClient(t, server, WithTlsCert(pemCert, keyCert), WithMicropayPolling(200*time.Millisecond, 10*time.Millisecond))
+}
+
+func testTradeMicropay() TradeMicropay {
+	var p TradeMicropay
+	p.Body = "付款码支付测试"
+	p.OutTradeNo = "TEST2023112717521212345678"
+	p.TotalFee = 1
+	p.SpbillCreateIp = "127.0.0.1"
+	p.AuthCode = "134567890123456780"
+	return p
+}
+
+func TestClient_TradeMicropayAndWait(t *testing.T) {
+	server, queries, reverses := newMicropayServer(t, 3, 0)
+	c := newMicropayClient(t, server)
+	r, err := c.TradeMicropayAndWait(context.Background(), testTradeMicropay())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TradeState != TradeStateSuccess || r.TransactionId == "" {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	if n := atomic.LoadInt32(queries); n != 3 {
+		t.Fatalf("expected 3 queries, got %d", n)
+	}
+	if n := atomic.LoadInt32(reverses); n != 0 {
+		t.Fatalf("expected no reverse, got %d", n)
+	}
+}
+
+func TestClient_TradeMicropayAndWaitReverse(t *testing.T) {
+	server, queries, reverses := newMicropayServer(t, 0, 0)
+	c := newMicropayClient(t, server)
+	_, err := c.TradeMicropayAndWait(context.Background(), testTradeMicropay())
+	if !errors.Is(err, ErrWxMicropayReversed) {
+		t.Fatalf("expected ErrWxMicropayReversed, got %v", err)
+	}
+	if n := atomic.LoadInt32(queries); n == 0 {
+		t.Fatal("expected order queries before reverse")
+	}
+	if n := atomic.LoadInt32(reverses); n != 1 {
+		t.Fatalf("expected 1 reverse, got %d", n)
+	}
+}
+
+// 调用方超时短于等待时间时仍需撤销订单
+func TestClient_TradeMicropayAndWaitContextTimeout(t *testing.T) {
+	server, _, reverses := newMicropayServer(t, 0, 0)
+	c := newMicropayClient(t, server)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.TradeMicropayAndWait(ctx, testTradeMicropay())
+	if !errors.Is(err, ErrWxMicropayReversed) {
+		t.Fatalf("expected ErrWxMicropayReversed, got %v", err)
+	}
+	if n := atomic.LoadInt32(reverses); n != 1 {
+		t.Fatalf("expected 1 reverse, got %d", n)
+	}
+}
+
+// 等待时间结束后撤销前再查询一次，已支付的订单不撤销
+func TestClient_TradeMicropayAndWaitFinalQuery(t *testing.T) {
+	server, queries, reverses := newMicropayServer(t, 2, 0)
+	pemCert, keyCert := newTestCert(t)
+	c := newTestClient(t, server, WithTlsCert(pemCert, keyCert), WithMicropayPolling(10*time.Millisecond, 50*time.Millisecond))
+	r, err := c.TradeMicropayAndWait(context.Background(), testTradeMicropay())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TradeState != TradeStateSuccess {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	if atomic.LoadInt32(queries) != 2 || atomic.LoadInt32(reverses) != 0 {
+		t.Fatalf("expected 2 queries and no reverse, got %d, %d", *queries, *reverses)
+	}
+}
+
+// 未加载证书时无法撤销订单，不发起支付
+func TestClient_TradeMicropayAndWaitTlsCertRequired(t *testing.T) {
+	server, queries, _ := newMicropayServer(t, 0, 0)
+	c := newTestClient(t, server)
+	if _, err := c.TradeMicropayAndWait(context.Background(), testTradeMicropay()); !errors.Is(err, ErrWxPemKeyNotFound) {
+		t.Fatalf("expected ErrWxPemKeyNotFound, got %v", err)
+	}
+	if n := atomic.LoadInt32(queries); n != 0 {
+		t.Fatalf("expected no query, got %d", n)
+	}
+}
+
+func TestClient_TradeMicropayAndWaitFail(t *testing.T) {
+	server, queries, reverses := newMicropayServer(t, 0, 0)
+	c := newMicropayClient(t, server)
+	p := testTradeMicropay()
+	p.AuthCode = "134567890123456789"
+	_, err := c.TradeMicropayAndWait(context.Background(), p)
+	var pErr PayError
+	if !errors.As(err, &pErr) || pErr.ErrCode != "AUTHCODEEXPIRE" {
+		t.Fatalf("expected AUTHCODEEXPIRE, got %v", err)
+	}
+	if atomic.LoadInt32(queries) != 0 || atomic.LoadInt32(reverses) != 0 {
+		t.Fatal("expected no query or reverse on definite failure")
+	}
+}
+
+func TestClient_TradeReverse(t *testing.T) {
+	server, _, reverses := newMicropayServer(t, 0, 1)
+	c := newMicropayClient(t, server)
+	var p TradeReverse
+	p.OutTradeNo = "TEST2023112717521212345678"
+	r, err := c.TradeReverse(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.NeedRecall() || r.ErrCode != PayErrCodeSystemError {
+		t.Fatalf("expected recall, got %+v", r)
+	}
+	if r, err = c.TradeReverse(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if r.NeedRecall() {
+		t.Fatalf("expected no recall, got %+v", r)
+	}
+	if n := atomic.LoadInt32(reverses); n != 2 {
+		t.Fatalf("expected 2 reverses, got %d", n)
+	}
+}
+
+func TestClient_TradeReverseTlsCertRequired(t *testing.T) {
+	server, _, reverses := newMicropayServer(t, 0, 0)
+	c := newTestClient(t, server)
+	var p TradeReverse
+	p.OutTradeNo = "TEST2023112717521212345678"
+	if _, err := c.TradeReverse(context.Background(), p); !errors.Is(err, ErrWxPemKeyNotFound) {
+		t.Fatalf("expected ErrWxPemKeyNotFound, got %v", err)
+	}
+	if n := atomic.LoadInt32(reverses); n != 0 {
+		t.Fatalf("expected no reverse, got %d", n)
+	}
+}
+
+func TestClient_TradeReverseUntilDone(t *testing.T) {
+	server, _, reverses := newMicropayServer(t, 0, 2)
+	c := newMicropayClient(t, server)
+	var p TradeReverse
+	p.OutTradeNo = "TEST2023112717521212345678"
+	if err := c.TradeReverseUntilDone(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(reverses); n != 3 {
+		t.Fatalf("expected 3 reverses, got %d", n)
+	}
+
+	server, _, _ = newMicropayServer(t, 0, kReverseMaxAttempts)
+	c = newMicropayClient(t, server)
+	if err := c.TradeReverseUntilDone(context.Background(), p); !errors.Is(err, ErrWxReverseRecall) {
+		t.Fatalf("expected ErrWxReverseRecall, got %v", err)
+	}
+
+	// 重试策略不与撤销重试叠加，SYSTEMERROR 最多请求3次
+	server, _, reverses = newMicropayServer(t, 0, 10)
+	pemCert, keyCert := newTestCert(t)
+	c = newTestClient(t, server, WithTlsCert(pemCert, keyCert), WithMicropayPolling(200*time.Millisecond, 10*time.Millisecond),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+	if err := c.TradeReverseUntilDone(context.Background(), p); !errors.Is(err, ErrWxReverseRecall) {
+		t.Fatalf("expected ErrWxReverseRecall, got %v", err)
+	}
+	if n := atomic.LoadInt32(reverses); n != kReverseMaxAttempts {
+		t.Fatalf("expected %d reverses, got %d", kReverseMaxAttempts, n)
+	}
+	// 单独调用撤销订单时按重试策略重试
+	atomic.StoreInt32(reverses, 0)
+	if _, err := c.TradeReverse(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(reverses); n != 3 {
+		t.Fatalf("expected 3 reverses with retry policy, got %d", n)
+	}
+}
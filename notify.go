@@ -0,0 +1,193 @@
+package wxpay
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+var ErrWxNotifyTooLarge = errors.New("wxpay: notify body too large")
+
+// ParsePayNotify 解析支付结果通知 https://pay.weixin.qq.com/wiki/doc/api/wxa/wxa_api.php?chapter=9_7
+// 读取请求内容并验证签名，通知链接为统一下单时传入的 notify_url
+func (c *Client) ParsePayNotify(req *http.Request) (result *PayNotification, err error) {
+	data, err := c.readNotifyBody(req)
+	if err != nil {
+		return
+	}
+	return c.parsePayNotify(data)
+}
+
+// PayNotifyHandler 支付结果通知处理器，验证通过后调用 fn，fn 返回错误时应答 FAIL，微信会重新发送通知
+func (c *Client) PayNotifyHandler(fn func(ctx context.Context, notification *PayNotification) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notification, err := c.ParsePayNotify(r)
+		if err == nil {
+			err = fn(r.Context(), notification)
+		}
+		WriteNotifyAck(w, err)
+	})
+}
+
+// ParseRefundNotify 解析退款结果通知 https://pay.weixin.qq.com/wiki/doc/api/wxa/wxa_api.php?chapter=9_16&index=11
+// 退款通知不带签名，通过商户支付密钥解密 req_info 得到退款信息
+func (c *Client) ParseRefundNotify(req *http.Request) (result *RefundNotification, err error) {
+	data, err := c.readNotifyBody(req)
+	if err != nil {
+		return
+	}
+	return c.parseRefundNotify(data)
+}
+
+// RefundNotifyHandler 退款结果通知处理器，解密成功后调用 fn，fn 返回错误时应答 FAIL，微信会重新发送通知
+func (c *Client) RefundNotifyHandler(fn func(ctx context.Context, notification *RefundNotification) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notification, err := c.ParseRefundNotify(r)
+		if err == nil {
+			err = fn(r.Context(), notification)
+		}
+		WriteNotifyAck(w, err)
+	})
+}
+
+// WriteNotifyAck 应答微信通知，err 为空时应答 SUCCESS，否则应答 FAIL，
+// 错误信息不会返回给微信，避免泄露内部错误，需要时由调用方记录日志
+func WriteNotifyAck(w http.ResponseWriter, err error) {
+	ack := NotifyAck{ReturnCode: ReturnCodeSuccess, ReturnMsg: "OK"}
+	if err != nil {
+		ack.ReturnCode = ReturnCodeFail
+		ack.ReturnMsg = string(ReturnCodeFail)
+	}
+	b, _ := xml.Marshal(ack)
+	w.Header().Set("Content-Type", kContentTypeXml)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// 读取通知内容
+func (c *Client) readNotifyBody(req *http.Request) (data []byte, err error) {
+	if data, err = readNotifyBody(req); err != nil {
+		return
+	}
+	if c.onReceivedData != nil {
+		c.onReceivedData(req.Method, data)
+	}
+	return
+}
+
+// 读取通知请求体，超过最大长度时返回 ErrWxNotifyTooLarge
+func readNotifyBody(req *http.Request) (data []byte, err error) {
+	defer req.Body.Close()
+	if data, err = io.ReadAll(io.LimitReader(req.Body, kNotifyMaxBodySize+1)); err != nil {
+		return nil, fmt.Errorf("wxpay: read notify body fail, %s", err.Error())
+	}
+	if len(data) > kNotifyMaxBodySize {
+		return nil, ErrWxNotifyTooLarge
+	}
+	return
+}
+
+// 解析支付结果通知内容
+func (c *Client) parsePayNotify(data []byte) (result *PayNotification, err error) {
+	resultMap := make(map[string]string)
+	if err = xml.Unmarshal(data, (*payXml)(&resultMap)); err != nil {
+		return
+	}
+	if _, ok := resultMap[kFieldReturnCode]; !ok {
+		return nil, ErrWxReturnCodeNotFound
+	}
+	if ReturnCode(resultMap[kFieldReturnCode]).IsFailure() {
+		return nil, PayError{ReturnCode: ReturnCode(resultMap[kFieldReturnCode]), ReturnMsg: resultMap["return_msg"]}
+	}
+	params := make(url.Values)
+	for key, value := range resultMap {
+		params.Add(key, value)
+	}
+	if err = c.VerifySign(params); err != nil {
+		return
+	}
+	result = new(PayNotification)
+	if err = xml.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+	// 代金券信息，$n为下标，从0开始编号
+	for n := 0; n < result.CouponCount; n++ {
+		coupon := PayNotifyCoupon{
+			CouponType: resultMap[fmt.Sprintf("coupon_type_%d", n)],
+			CouponId:   resultMap[fmt.Sprintf("coupon_id_%d", n)],
+		}
+		if fee := resultMap[fmt.Sprintf("coupon_fee_%d", n)]; fee != "" {
+			couponFee, err := strconv.ParseInt(fee, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("wxpay: parse coupon_fee_%d fail, %s", n, err.Error())
+			}
+			coupon.CouponFee = Fen(couponFee)
+		}
+		result.Coupons = append(result.Coupons, coupon)
+	}
+	return
+}
+
+// 解析退款结果通知内容
+func (c *Client) parseRefundNotify(data []byte) (result *RefundNotification, err error) {
+	resultMap := make(map[string]string)
+	if err = xml.Unmarshal(data, (*payXml)(&resultMap)); err != nil {
+		return
+	}
+	if _, ok := resultMap[kFieldReturnCode]; !ok {
+		return nil, ErrWxReturnCodeNotFound
+	}
+	if ReturnCode(resultMap[kFieldReturnCode]).IsFailure() {
+		return nil, PayError{ReturnCode: ReturnCode(resultMap[kFieldReturnCode]), ReturnMsg: resultMap["return_msg"]}
+	}
+	reqInfo, err := c.decryptReqInfo(resultMap[kFieldReqInfo])
+	if err != nil {
+		return
+	}
+	result = new(RefundNotification)
+	if err = xml.Unmarshal(reqInfo, result); err != nil {
+		return nil, fmt.Errorf("wxpay: parse req_info fail, %s", err.Error())
+	}
+	result.AppID = resultMap[kFieldAppId]
+	result.MchID = resultMap[kFieldMchId]
+	return
+}
+
+// 解密退款通知 req_info，对商户支付密钥做MD5得到32位小写key，使用 AES-256-ECB 解密
+func (c *Client) decryptReqInfo(reqInfo string) ([]byte, error) {
+	if reqInfo == "" {
+		return nil, errors.New("wxpay: req_info not found")
+	}
+	cipherText, err := base64.StdEncoding.DecodeString(reqInfo)
+	if err != nil {
+		return nil, fmt.Errorf("wxpay: decode req_info fail, %s", err.Error())
+	}
+	h := md5.Sum([]byte(c.mchSecret))
+	block, err := aes.NewCipher([]byte(hex.EncodeToString(h[:])))
+	if err != nil {
+		return nil, err
+	}
+	if len(cipherText) == 0 || len(cipherText)%block.BlockSize() != 0 {
+		return nil, errors.New("wxpay: req_info is not a multiple of the block size")
+	}
+	plainText := make([]byte, len(cipherText))
+	for i := 0; i < len(cipherText); i += block.BlockSize() {
+		block.Decrypt(plainText[i:i+block.BlockSize()], cipherText[i:i+block.BlockSize()])
+	}
+	// 去除PKCS#7填充
+	padding := int(plainText[len(plainText)-1])
+	if padding == 0 || padding > block.BlockSize() || !bytes.Equal(plainText[len(plainText)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, errors.New("wxpay: decrypt req_info fail, invalid padding")
+	}
+	return plainText[:len(plainText)-padding], nil
+}
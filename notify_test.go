@@ -0,0 +1,234 @@
+package wxpay
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// 生成带签名的通知内容
+func newTestNotifyBody(t *testing.T, c *Client, m map[string]string) []byte {
+	t.Helper()
+	values := make(url.Values)
+	for k, v := range m {
+		values.Set(k, v)
+	}
+	m[kFieldSign] = c.sign(values)
+	b, err := xml.Marshal(payXml(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func newTestPayNotify() map[string]string {
+	return map[string]string{
+		"return_code":    "SUCCESS",
+		"result_code":    "SUCCESS",
+		"appid":          testAppId,
+		"mch_id":         testMchId,
+		"nonce_str":      "5K8264ILTKCH16CQ2502SI8ZNMTM67VS",
+		"openid":         "oUpF8uMEb4qRXf22hE3X68TekukE",
+		"trade_type":     "JSAPI",
+		"bank_type":      "CMC",
+		"total_fee":      "101",
+		"cash_fee":       "1",
+		"coupon_fee":     "100",
+		"coupon_count":   "2",
+		"coupon_type_0":  "CASH",
+		"coupon_id_0":    "10000",
+		"coupon_fee_0":   "60",
+		"coupon_type_1":  "NO_CASH",
+		"coupon_id_1":    "10001",
+		"coupon_fee_1":   "40",
+		"transaction_id": "1004400740201409030005092168",
+		"out_trade_no":   "TEST2023112717521212345678",
+		"time_end":       "20231127175212",
+	}
+}
+
+func TestClient_PayNotifyHandler(t *testing.T) {
+	c, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := newTestNotifyBody(t, c, newTestPayNotify())
+
+	var got *PayNotification
+	handler := c.PayNotifyHandler(func(ctx context.Context, notification *PayNotification) error {
+		got = notification
+		return nil
+	})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body)))
+	var ack NotifyAck
+	if err = xml.Unmarshal(rec.Body.Bytes(), &ack); err != nil {
+		t.Fatal(err)
+	}
+	if ack.ReturnCode != ReturnCodeSuccess {
+		t.Fatalf("ack = %+v, want SUCCESS", ack)
+	}
+	if got == nil || got.OutTradeNo != "TEST2023112717521212345678" || got.TotalFee != 101 {
+		t.Fatalf("unexpected notification %+v", got)
+	}
+	want := []PayNotifyCoupon{{"CASH", "10000", 60}, {"NO_CASH", "10001", 40}}
+	if len(got.Coupons) != len(want) {
+		t.Fatalf("coupons = %+v, want %+v", got.Coupons, want)
+	}
+	for i := range want {
+		if got.Coupons[i] != want[i] {
+			t.Errorf("coupons[%d] = %+v, want %+v", i, got.Coupons[i], want[i])
+		}
+	}
+
+	// 业务处理失败时应答 FAIL，微信会重新发送通知
+	handler = c.PayNotifyHandler(func(ctx context.Context, notification *PayNotification) error {
+		return errors.New("db unavailable")
+	})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body)))
+	if err = xml.Unmarshal(rec.Body.Bytes(), &ack); err != nil {
+		t.Fatal(err)
+	}
+	// 内部错误信息不返回给微信
+	if ack.ReturnCode != ReturnCodeFail || ack.ReturnMsg != "FAIL" {
+		t.Fatalf("ack = %+v, want FAIL", ack)
+	}
+}
+
+// 服务商模式下的通知包含子商户参数
+func TestClient_ParsePayNotifySubMerchant(t *testing.T) {
+	c, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newTestPayNotify()
+	m["sub_appid"] = "wx1234567890abcdef"
+	m["sub_mch_id"] = "1900000110"
+	m["sub_openid"] = "oUpF8uN95-Ptaags6E_roPHg7AG0"
+	m["sub_is_subscribe"] = "Y"
+	r, err := c.parsePayNotify(newTestNotifyBody(t, c, m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SubAppId != "wx1234567890abcdef" || r.SubMchId != "1900000110" || r.SubOpenId != "oUpF8uN95-Ptaags6E_roPHg7AG0" || r.SubIsSubscribe != "Y" {
+		t.Fatalf("unexpected sub merchant fields %+v", r)
+	}
+}
+
+func TestClient_ParsePayNotifyTooLarge(t *testing.T) {
+	c, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := bytes.Repeat([]byte(" "), kNotifyMaxBodySize+1)
+	if _, err = c.ParsePayNotify(httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body))); !errors.Is(err, ErrWxNotifyTooLarge) {
+		t.Fatalf("expected ErrWxNotifyTooLarge, got %v", err)
+	}
+}
+
+func TestClient_ParsePayNotifyInvalidSign(t *testing.T) {
+	c, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newTestPayNotify()
+	body := newTestNotifyBody(t, c, m)
+	body = bytes.Replace(body, []byte("<total_fee>101</total_fee>"), []byte("<total_fee>1</total_fee>"), 1)
+	if _, err = c.ParsePayNotify(httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body))); err == nil {
+		t.Fatal("expected verify failure for tampered notification")
+	}
+}
+
+// 按微信规则加密退款信息
+func encryptTestReqInfo(t *testing.T, mchSecret string, plainText []byte) string {
+	t.Helper()
+	h := md5.Sum([]byte(mchSecret))
+	block, err := aes.NewCipher([]byte(hex.EncodeToString(h[:])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	padding := block.BlockSize() - len(plainText)%block.BlockSize()
+	plainText = append(plainText, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipherText := make([]byte, len(plainText))
+	for i := 0; i < len(plainText); i += block.BlockSize() {
+		block.Encrypt(cipherText[i:i+block.BlockSize()], plainText[i:i+block.BlockSize()])
+	}
+	return base64.StdEncoding.EncodeToString(cipherText)
+}
+
+func TestClient_RefundNotifyHandler(t *testing.T) {
+	c, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqInfo := `<root>
+<out_refund_no><![CDATA[REFUND2023112717521212345678]]></out_refund_no>
+<out_trade_no><![CDATA[TEST2023112717521212345678]]></out_trade_no>
+<refund_account><![CDATA[REFUND_SOURCE_RECHARGE_FUNDS]]></refund_account>
+<refund_fee><![CDATA[100]]></refund_fee>
+<refund_id><![CDATA[50000408942018111907145868882]]></refund_id>
+<refund_recv_accout><![CDATA[支付用户零钱]]></refund_recv_accout>
+<refund_request_source><![CDATA[API]]></refund_request_source>
+<refund_status><![CDATA[SUCCESS]]></refund_status>
+<settlement_refund_fee><![CDATA[100]]></settlement_refund_fee>
+<settlement_total_fee><![CDATA[100]]></settlement_total_fee>
+<success_time><![CDATA[2023-11-27 17:52:12]]></success_time>
+<total_fee><![CDATA[100]]></total_fee>
+<transaction_id><![CDATA[4200000215201811190261405420]]></transaction_id>
+</root>`
+	body, err := xml.Marshal(payXml{
+		"return_code": "SUCCESS",
+		"appid":       testAppId,
+		"mch_id":      testMchId,
+		"nonce_str":   "TeqClE3i0mvn3DrK",
+		"req_info":    encryptTestReqInfo(t, testMchSecret, []byte(reqInfo)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got *RefundNotification
+	handler := c.RefundNotifyHandler(func(ctx context.Context, notification *RefundNotification) error {
+		got = notification
+		return nil
+	})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/refund_notify", bytes.NewReader(body)))
+	var ack NotifyAck
+	if err = xml.Unmarshal(rec.Body.Bytes(), &ack); err != nil {
+		t.Fatal(err)
+	}
+	if ack.ReturnCode != ReturnCodeSuccess {
+		t.Fatalf("ack = %+v, want SUCCESS", ack)
+	}
+	if got == nil || got.RefundStatus != "SUCCESS" || got.RefundFee != 100 || got.MchID != testMchId ||
+		got.SuccessTime != "2023-11-27 17:52:12" || got.RefundRecvAccout != "支付用户零钱" || got.SettlementRefundFee != 100 {
+		t.Fatalf("unexpected notification %+v", got)
+	}
+
+	// 商户密钥不匹配时无法解密，应答 FAIL
+	other, err := New(testAppId, testSecret, WithMchInformation(testMchId, "00000000000000000000000000000000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	other.RefundNotifyHandler(func(ctx context.Context, notification *RefundNotification) error {
+		return nil
+	}).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/refund_notify", bytes.NewReader(body)))
+	if err = xml.Unmarshal(rec.Body.Bytes(), &ack); err != nil {
+		t.Fatal(err)
+	}
+	if ack.ReturnCode != ReturnCodeFail {
+		t.Fatalf("ack = %+v, want FAIL", ack)
+	}
+}
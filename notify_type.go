@@ -0,0 +1,71 @@
+package wxpay
+
+import "encoding/xml"
+
+/* 支付结果通知 */
+
+// PayNotification 支付结果通知 https://pay.weixin.qq.com/wiki/doc/api/wxa/wxa_api.php?chapter=9_7
+type PayNotification struct {
+	PayError
+	SubMerchantRsp
+	AppID              string            `xml:"appid" json:"appid"`                                           // 微信分配的小程序ID
+	MchID              string            `xml:"mch_id" json:"mch_id"`                                         // 微信支付分配的商户号
+	DeviceInfo         string            `xml:"device_info" json:"device_info"`                               // 微信支付分配的终端设备号
+	NonceStr           string            `xml:"nonce_str" json:"nonce_str"`                                   // 随机字符串，不长于32位
+	Sign               string            `xml:"sign" json:"sign"`                                             // 签名
+	SignType           string            `xml:"sign_type" json:"sign_type"`                                   // 签名类型，目前支持HMAC-SHA256和MD5，默认为MD5
+	OpenId             string            `xml:"openid" json:"openid"`                                         // 用户在商户appid下的唯一标识
+	IsSubscribe        string            `xml:"is_subscribe" json:"is_subscribe"`                             // 用户是否关注公众账号，Y-关注，N-未关注
+	SubOpenId          string            `xml:"sub_openid,omitempty" json:"sub_openid,omitempty"`             // 用户在子商户appid下的唯一标识，服务商模式下返回
+	SubIsSubscribe     string            `xml:"sub_is_subscribe,omitempty" json:"sub_is_subscribe,omitempty"` // 用户是否关注子公众账号，服务商模式下返回
+	TradeType          string            `xml:"trade_type" json:"trade_type"`                                 // JSAPI、NATIVE、APP
+	BankType           string            `xml:"bank_type" json:"bank_type"`                                   // 银行类型，采用字符串类型的银行标识
+	TotalFee           Fen               `xml:"total_fee" json:"total_fee"`                                   // 订单总金额，单位为分
+	SettlementTotalFee Fen               `xml:"settlement_total_fee,omitempty" json:"settlement_total_fee"`   // 应结订单金额=订单金额-非充值代金券金额，应结订单金额<=订单金额。
+	FeeType            string            `xml:"fee_type" json:"fee_type"`                                     // 货币类型，符合ISO4217标准的三位字母代码，默认人民币：CNY
+	CashFee            Fen               `xml:"cash_fee" json:"cash_fee"`                                     // 现金支付金额订单现金支付金额
+	CashFeeType        string            `xml:"cash_fee_type" json:"cash_fee_type"`                           // 货币类型，符合ISO4217标准的三位字母代码，默认人民币：CNY
+	CouponFee          Fen               `xml:"coupon_fee,omitempty" json:"coupon_fee"`                       // 总代金券金额，代金券金额<=订单金额，订单金额-代金券金额=现金支付金额
+	CouponCount        int               `xml:"coupon_count,omitempty" json:"coupon_count"`                   // 代金券使用数量
+	Coupons            []PayNotifyCoupon `xml:"-" json:"coupons"`                                             // 代金券列表，由 coupon_type_$n、coupon_id_$n、coupon_fee_$n 解析而来
+	TransactionId      string            `xml:"transaction_id" json:"transaction_id"`                         // 微信支付订单号
+	OutTradeNo         string            `xml:"out_trade_no" json:"out_trade_no"`                             // 商户系统内部订单号，要求32个字符内，只能是数字、大小写字母_-|*@ ，且在同一个商户号下唯一。
+	Attach             string            `xml:"attach" json:"attach"`                                         // 商家数据包，原样返回
+	TimeEnd            string            `xml:"time_end" json:"time_end"`                                     // 支付完成时间，格式为yyyyMMddHHmmss，如2009年12月25日9点10分10秒表示为20091225091010。
+}
+
+// PayNotifyCoupon 支付结果通知中的代金券信息
+type PayNotifyCoupon struct {
+	CouponType string `json:"coupon_type"` // 代金券类型，CASH--充值代金券 NO_CASH---非充值代金券
+	CouponId   string `json:"coupon_id"`   // 代金券ID
+	CouponFee  Fen    `json:"coupon_fee"`  // 单个代金券支付金额
+}
+
+// NotifyAck 通知应答，商户处理后同步返回给微信
+type NotifyAck struct {
+	XMLName    xml.Name   `xml:"xml"`
+	ReturnCode ReturnCode `xml:"return_code"` // SUCCESS/FAIL，返回FAIL时微信会重新发送通知
+	ReturnMsg  string     `xml:"return_msg"`  // 返回信息，如非空，为错误原因
+}
+
+/* 退款结果通知 */
+
+// RefundNotification 退款结果通知 https://pay.weixin.qq.com/wiki/doc/api/wxa/wxa_api.php?chapter=9_16&index=11
+type RefundNotification struct {
+	AppID               string `xml:"-" json:"appid"`                                             // 微信分配的公众账号ID，取自外层数据
+	MchID               string `xml:"-" json:"mch_id"`                                            // 微信支付分配的商户号，取自外层数据
+	TransactionId       string `xml:"transaction_id" json:"transaction_id"`                       // 微信订单号
+	OutTradeNo          string `xml:"out_trade_no" json:"out_trade_no"`                           // 商户系统内部的订单号
+	RefundId            string `xml:"refund_id" json:"refund_id"`                                 // 微信退款单号
+	OutRefundNo         string `xml:"out_refund_no" json:"out_refund_no"`                         // 商户退款单号
+	TotalFee            Fen    `xml:"total_fee" json:"total_fee"`                                 // 订单总金额，单位为分，只能为整数
+	SettlementTotalFee  Fen    `xml:"settlement_total_fee,omitempty" json:"settlement_total_fee"` // 当该订单有使用非充值券时，返回此字段。应结订单金额=订单金额-非充值代金券金额，应结订单金额<=订单金额。
+	RefundFee           Fen    `xml:"refund_fee" json:"refund_fee"`                               // 退款总金额，单位为分
+	SettlementRefundFee Fen    `xml:"settlement_refund_fee" json:"settlement_refund_fee"`         // 退款金额，退款金额=申请退款金额-非充值代金券退款金额，退款金额<=申请退款金额
+	RefundStatus        string `xml:"refund_status" json:"refund_status"`                         // 退款状态，SUCCESS-退款成功 CHANGE-退款异常 REFUNDCLOSE—退款关闭
+	SuccessTime         string `xml:"success_time,omitempty" json:"success_time"`                 // 退款成功时间，资金退款至用户账号的时间，格式2017-12-15 09:46:01
+	RefundRecvAccout    string `xml:"refund_recv_accout" json:"refund_recv_accout"`               // 退款入账账户，取当前退款单的退款入账方 1）退回银行卡：{银行名称}{卡类型}{卡尾号} 2）退回支付用户零钱：支付用户零钱 3）退还商户：商户基本账户、商户结算银行账户 4）退回支付用户零钱通：支付用户零钱通
+	RefundAccount       string `xml:"refund_account" json:"refund_account"`                       // 退款资金来源，REFUND_SOURCE_RECHARGE_FUNDS 可用余额退款/基本账户 REFUND_SOURCE_UNSETTLED_FUNDS 未结算资金退款
+	RefundRequestSource string `xml:"refund_request_source" json:"refund_request_source"`         // 退款发起来源，API接口 VENDOR_PLATFORM商户平台
+	CashRefundFee       Fen    `xml:"cash_refund_fee" json:"cash_refund_fee"`                     // 用户退款金额，退款给用户的金额，不包含所有优惠券金额
+}
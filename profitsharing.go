@@ -0,0 +1,60 @@
+package wxpay
+
+import "context"
+
+// ProfitSharingAddReceiver 添加分账接收方 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_3&index=4
+// POST https://api.mch.weixin.qq.com/pay/profitsharingaddreceiver
+func (c *Client) ProfitSharingAddReceiver(ctx context.Context, param ProfitSharingAddReceiver) (result *ProfitSharingReceiverRsp, err error) {
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
+
+// ProfitSharingRemoveReceiver 删除分账接收方 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_4&index=5
+// POST https://api.mch.weixin.qq.com/pay/profitsharingremovereceiver
+func (c *Client) ProfitSharingRemoveReceiver(ctx context.Context, param ProfitSharingRemoveReceiver) (result *ProfitSharingReceiverRsp, err error) {
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
+
+// ProfitSharing 请求单次分账 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_1&index=1
+// POST https://api.mch.weixin.qq.com/secapi/pay/profitsharing
+// 请求多次分账 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_6&index=2
+// POST https://api.mch.weixin.qq.com/secapi/pay/multiprofitsharing
+// 需要加载证书，返回成功仅表示受理成功，分账结果需通过 ProfitSharingQuery 查询
+func (c *Client) ProfitSharing(ctx context.Context, param ProfitSharing) (result *ProfitSharingRsp, err error) {
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
+
+// ProfitSharingQuery 查询分账结果 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_2&index=3
+// POST https://api.mch.weixin.qq.com/pay/profitsharingquery
+func (c *Client) ProfitSharingQuery(ctx context.Context, param ProfitSharingQuery) (result *ProfitSharingQueryRsp, err error) {
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
+
+// ProfitSharingReturn 分账回退 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_7&index=7
+// POST https://api.mch.weixin.qq.com/secapi/pay/profitsharingreturn
+// 需要加载证书，return_account_type 为空时自动填充为 MERCHANT_ID
+func (c *Client) ProfitSharingReturn(ctx context.Context, param ProfitSharingReturn) (result *ProfitSharingReturnRsp, err error) {
+	if param.ReturnAccountType == "" {
+		param.ReturnAccountType = kReturnAccountTypeMerchantId
+	}
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
+
+// ProfitSharingReturnQuery 分账回退结果查询 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_8&index=8
+// POST https://api.mch.weixin.qq.com/pay/profitsharingreturnquery
+func (c *Client) ProfitSharingReturnQuery(ctx context.Context, param ProfitSharingReturnQuery) (result *ProfitSharingReturnRsp, err error) {
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
+
+// ProfitSharingFinish 完结分账 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_5&index=6
+// POST https://api.mch.weixin.qq.com/secapi/pay/profitsharingfinish
+// 需要加载证书，多次分账完成后调用，剩余待分账金额解冻给本商户
+func (c *Client) ProfitSharingFinish(ctx context.Context, param ProfitSharingFinish) (result *ProfitSharingRsp, err error) {
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
@@ -0,0 +1,207 @@
+package wxpay
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// 模拟分账接口，校验 HMAC-SHA256 签名、JSON 格式的分账接收方与资金类接口的客户端证书
+func newProfitSharingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	signer, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 分账接口的返回数据使用 HMAC-SHA256 签名，不带 sign_type
+	write := func(w http.ResponseWriter, m payXml) {
+		writeTestPayXml(w, signer, m, SignTypeHMACSHA256)
+	}
+	fail := func(w http.ResponseWriter, msg string) {
+		b, _ := xml.Marshal(payXml{"return_code": "FAIL", "return_msg": msg})
+		w.Write(b)
+	}
+	read := func(w http.ResponseWriter, r *http.Request) (payXml, bool) {
+		if strings.HasPrefix(r.URL.Path, "/secapi/") && (r.TLS == nil || len(r.TLS.PeerCertificates) == 0) {
+			fail(w, "cert required")
+			return nil, false
+		}
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			fail(w, err.Error())
+			return nil, false
+		}
+		if m[kFieldSignType] != SignTypeHMACSHA256 {
+			fail(w, "sign_type must be HMAC-SHA256")
+			return nil, false
+		}
+		return m, true
+	}
+	receiver := func(w http.ResponseWriter, r *http.Request) {
+		m, ok := read(w, r)
+		if !ok {
+			return
+		}
+		var receiver ProfitSharingReceiver
+		if err := json.Unmarshal([]byte(m["receiver"]), &receiver); err != nil || receiver.Account != testMchId {
+			fail(w, "invalid receiver")
+			return
+		}
+		write(w, payXml{"return_code": "SUCCESS", "result_code": "SUCCESS", "mch_id": testMchId, "appid": testAppId, "receiver": m["receiver"]})
+	}
+	order := func(w http.ResponseWriter, r *http.Request) {
+		m, ok := read(w, r)
+		if !ok {
+			return
+		}
+		if r.URL.Path != "/secapi/pay/profitsharingfinish" {
+			var receivers []ProfitSharingReceiverAmount
+			if err := json.Unmarshal([]byte(m["receivers"]), &receivers); err != nil || len(receivers) != 1 || receivers[0].Amount != 10 {
+				fail(w, "invalid receivers")
+				return
+			}
+		}
+		status := ProfitSharingStatusProcessing
+		if r.URL.Path == "/secapi/pay/profitsharing" {
+			status = ProfitSharingStatusFinished
+		}
+		write(w, payXml{"return_code": "SUCCESS", "result_code": "SUCCESS", "transaction_id": m["transaction_id"], "out_order_no": m["out_order_no"], "order_id": "3008450740201411110007820472", "status": status})
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pay/profitsharingaddreceiver", receiver)
+	mux.HandleFunc("/pay/profitsharingremovereceiver", receiver)
+	mux.HandleFunc("/secapi/pay/profitsharing", order)
+	mux.HandleFunc("/secapi/pay/multiprofitsharing", order)
+	mux.HandleFunc("/secapi/pay/profitsharingfinish", order)
+	mux.HandleFunc("/pay/profitsharingquery", func(w http.ResponseWriter, r *http.Request) {
+		m, ok := read(w, r)
+		if !ok {
+			return
+		}
+		if m[kFieldAppId] != "" {
+			fail(w, "appid not allowed")
+			return
+		}
+		write(w, payXml{
+			"return_code":    "SUCCESS",
+			"result_code":    "SUCCESS",
+			"transaction_id": m["transaction_id"],
+			"out_order_no":   m["out_order_no"],
+			"status":         ProfitSharingStatusFinished,
+			"receivers":      `[{"type":"MERCHANT_ID","account":"` + testMchId + `","amount":10,"description":"分到商户","result":"SUCCESS","finish_time":"20180608170132","detail_id":"3601201812092010212345678"}]`,
+		})
+	})
+	returns := func(w http.ResponseWriter, r *http.Request) {
+		m, ok := read(w, r)
+		if !ok {
+			return
+		}
+		if m["return_account_type"] != "" && m["return_account_type"] != kReturnAccountTypeMerchantId {
+			fail(w, "invalid return_account_type")
+			return
+		}
+		write(w, payXml{"return_code": "SUCCESS", "result_code": "SUCCESS", "out_order_no": m["out_order_no"], "out_return_no": m["out_return_no"], "return_no": "3008450740201411110007820472", "return_amount": "10", "result": ProfitSharingResultSuccess})
+	}
+	mux.HandleFunc("/secapi/pay/profitsharingreturn", returns)
+	mux.HandleFunc("/pay/profitsharingreturnquery", returns)
+	server := httptest.NewUnstartedServer(mux)
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_ProfitSharing(t *testing.T) {
+	server := newProfitSharingServer(t)
+	pemCert, keyCert := newTestCert(t)
+	// 客户端使用MD5签名时分账接口仍使用 HMAC-SHA256
+	c := newTestClient(t, server, WithTlsCert(pemCert, keyCert))
+	ctx := context.Background()
+
+	receiver := ProfitSharingReceiver{Type: ReceiverTypeMerchantId, Account: testMchId, Name: "示例商户全称", RelationType: RelationTypeStore}
+	if _, err := c.ProfitSharingAddReceiver(ctx, ProfitSharingAddReceiver{Receiver: receiver}); err != nil {
+		t.Fatal(err)
+	}
+
+	receivers := ProfitSharingReceivers{{Type: ReceiverTypeMerchantId, Account: testMchId, Amount: 10, Description: "分到商户"}}
+	r, err := c.ProfitSharing(ctx, ProfitSharing{TransactionId: "4208450740201411110007820472", OutOrderNo: "P20150806125346", Receivers: receivers})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != ProfitSharingStatusFinished {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	if r, err = c.ProfitSharing(ctx, ProfitSharing{Multi: true, TransactionId: "4208450740201411110007820472", OutOrderNo: "P20150806125347", Receivers: receivers}); err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != ProfitSharingStatusProcessing {
+		t.Fatalf("unexpected multi result %+v", r)
+	}
+
+	q, err := c.ProfitSharingQuery(ctx, ProfitSharingQuery{TransactionId: "4208450740201411110007820472", OutOrderNo: "P20150806125346"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Receivers) != 1 || q.Receivers[0].Result != ProfitSharingResultSuccess || q.Receivers[0].Amount != 10 {
+		t.Fatalf("unexpected query result %+v", q)
+	}
+
+	ret, err := c.ProfitSharingReturn(ctx, ProfitSharingReturn{OutOrderNo: "P20150806125346", OutReturnNo: "R20190516001", ReturnAccount: testMchId, ReturnAmount: 10, Description: "用户退款"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Result != ProfitSharingResultSuccess || ret.ReturnAmount != 10 {
+		t.Fatalf("unexpected return result %+v", ret)
+	}
+	if _, err = c.ProfitSharingReturnQuery(ctx, ProfitSharingReturnQuery{OutOrderNo: "P20150806125346", OutReturnNo: "R20190516001"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = c.ProfitSharingFinish(ctx, ProfitSharingFinish{TransactionId: "4208450740201411110007820472", OutOrderNo: "P20150806125347", Description: "分账已完成"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.ProfitSharingRemoveReceiver(ctx, ProfitSharingRemoveReceiver{Receiver: ProfitSharingReceiver{Type: ReceiverTypeMerchantId, Account: testMchId}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 分账接口返回数据不带 sign_type，按 HMAC-SHA256 验证签名
+func TestClient_ProfitSharingVerifySign(t *testing.T) {
+	c, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p ProfitSharing
+	for signType, valid := range map[string]bool{SignTypeHMACSHA256: true, SignTypeMD5: false} {
+		m := map[string]string{"return_code": "SUCCESS", "result_code": "SUCCESS", "order_id": "3008450740201411110007820472"}
+		values := make(url.Values)
+		for k, v := range m {
+			values.Set(k, v)
+		}
+		m[kFieldSign] = signers[signType].Sign(c.formatBizQueryParaMap(values), testMchSecret)
+		b, _ := xml.Marshal(payXml(m))
+		var rsp *ProfitSharingRsp
+		err = c.decode(b, "POST", p.ReturnType(), p.NeedVerify(), p.SignType(), &rsp)
+		if valid != (err == nil) {
+			t.Fatalf("%s signed response, got %v", signType, err)
+		}
+	}
+}
+
+func TestProfitSharing_Validate(t *testing.T) {
+	p := ProfitSharing{
+		OutOrderNo: "P2015#0806",
+		Receivers:  ProfitSharingReceivers{{Type: "OPENID", Account: "o123", Amount: 0, Description: "分账"}},
+	}
+	fields := invalidFields(t, p.Validate())
+	want := []string{"transaction_id", "out_order_no", "receivers.type", "receivers.amount"}
+	if strings.Join(fields, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+}
@@ -0,0 +1,361 @@
+package wxpay
+
+import "encoding/json"
+
+// 分账接收方类型
+const (
+	ReceiverTypeMerchantId        = "MERCHANT_ID"         // 商户号（mch_id或者sub_mch_id）
+	ReceiverTypePersonalOpenId    = "PERSONAL_OPENID"     // 个人openid（由父商户APPID转换得到）
+	ReceiverTypePersonalSubOpenId = "PERSONAL_SUB_OPENID" // 个人sub_openid（由子商户APPID转换得到）
+)
+
+// 分账接收方与商户的关系类型
+const (
+	RelationTypeServiceProvider = "SERVICE_PROVIDER" // 服务商
+	RelationTypeStore           = "STORE"            // 门店
+	RelationTypeStaff           = "STAFF"            // 员工
+	RelationTypeStoreOwner      = "STORE_OWNER"      // 店主
+	RelationTypePartner         = "PARTNER"          // 合作伙伴
+	RelationTypeHeadquarter     = "HEADQUARTER"      // 总部
+	RelationTypeBrand           = "BRAND"            // 品牌方
+	RelationTypeDistributor     = "DISTRIBUTOR"      // 分销商
+	RelationTypeUser            = "USER"             // 用户
+	RelationTypeSupplier        = "SUPPLIER"         // 供应商
+	RelationTypeCustom          = "CUSTOM"           // 自定义
+)
+
+// 分账单状态
+const (
+	ProfitSharingStatusAccepted   = "ACCEPTED"   // 受理成功
+	ProfitSharingStatusProcessing = "PROCESSING" // 处理中
+	ProfitSharingStatusFinished   = "FINISHED"   // 处理完成
+	ProfitSharingStatusClosed     = "CLOSED"     // 处理失败，已关单
+)
+
+// 分账接收方分账结果、分账回退结果
+const (
+	ProfitSharingResultPending    = "PENDING"    // 待分账
+	ProfitSharingResultProcessing = "PROCESSING" // 分账回退处理中
+	ProfitSharingResultSuccess    = "SUCCESS"    // 成功
+	ProfitSharingResultClosed     = "CLOSED"     // 已关闭
+	ProfitSharingResultFailed     = "FAILED"     // 失败
+)
+
+const (
+	kProfitSharingMaxReceivers     = 50            // 单次分账接收方数量上限
+	kReturnAccountTypeMerchantId   = "MERCHANT_ID" // 分账回退方类型，商户号
+	kProfitSharingDescriptionLimit = 80            // 分账描述长度上限
+)
+
+// ProfitSharingReceiver 分账接收方，添加、删除分账接收方时以JSON格式传递
+type ProfitSharingReceiver struct {
+	Type           string `json:"type"`                      // 分账接收方类型，MERCHANT_ID、PERSONAL_OPENID、PERSONAL_SUB_OPENID
+	Account        string `json:"account"`                   // 分账接收方帐号，类型是MERCHANT_ID时是商户号，类型是PERSONAL_OPENID时是个人openid
+	Name           string `json:"name,omitempty"`            // 分账接收方全称，类型是MERCHANT_ID时必填商户全称，类型是PERSONAL_OPENID时选填个人姓名
+	RelationType   string `json:"relation_type,omitempty"`   // 与分账方的关系类型，添加分账接收方时必填
+	CustomRelation string `json:"custom_relation,omitempty"` // 自定义的分账关系，relation_type为CUSTOM时必填
+}
+
+// 校验接收方类型与帐号，prefix 为字段名前缀
+func validateReceiver(v *validator, prefix, typ, account string) {
+	switch typ {
+	case ReceiverTypeMerchantId, ReceiverTypePersonalOpenId, ReceiverTypePersonalSubOpenId:
+	default:
+		v.add(prefix+".type", "must be MERCHANT_ID, PERSONAL_OPENID or PERSONAL_SUB_OPENID")
+	}
+	v.required(prefix+".account", account)
+}
+
+// ProfitSharingReceiverAmount 分账接收方及分账金额，请求分账时以JSON数组格式传递，查询分账时返回分账结果
+type ProfitSharingReceiverAmount struct {
+	Type        string `json:"type"`                  // 分账接收方类型
+	Account     string `json:"account"`               // 分账接收方帐号
+	Amount      Fen    `json:"amount"`                // 分账金额，单位为分
+	Description string `json:"description"`           // 分账的原因描述，分账账单中需要体现
+	Name        string `json:"name,omitempty"`        // 分账个人接收方姓名，填写时校验与实名是否一致
+	Result      string `json:"result,omitempty"`      // 分账结果，PENDING、SUCCESS、CLOSED，仅查询分账时返回
+	FailReason  string `json:"fail_reason,omitempty"` // 分账失败原因，仅查询分账时返回
+	FinishTime  string `json:"finish_time,omitempty"` // 分账完成时间，仅查询分账时返回
+	DetailId    string `json:"detail_id,omitempty"`   // 分账明细单号，仅查询分账时返回
+}
+
+// ProfitSharingReceivers 分账接收方列表，在XML中以JSON字符串传递
+type ProfitSharingReceivers []ProfitSharingReceiverAmount
+
+// UnmarshalText 解析响应中JSON格式的分账接收方列表
+func (r *ProfitSharingReceivers) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		return nil
+	}
+	return json.Unmarshal(text, (*[]ProfitSharingReceiverAmount)(r))
+}
+
+func (r ProfitSharingReceivers) validate(v *validator) {
+	if len(r) == 0 || len(r) > kProfitSharingMaxReceivers {
+		v.add("receivers", "must have 1-50 receivers")
+		return
+	}
+	for _, receiver := range r {
+		validateReceiver(v, "receivers", receiver.Type, receiver.Account)
+		v.amount("receivers.amount", receiver.Amount)
+		if v.required("receivers.description", receiver.Description) {
+			v.maxLen("receivers.description", receiver.Description, kProfitSharingDescriptionLimit)
+		}
+	}
+}
+
+// 分账接口只支持HMAC-SHA256签名
+type profitSharingParam struct {
+	AuxParam
+}
+
+func (p profitSharingParam) ReturnType() string {
+	return "xml"
+}
+
+func (p profitSharingParam) SignType() string {
+	return SignTypeHMACSHA256
+}
+
+/* 添加分账接收方 */
+
+// ProfitSharingAddReceiver 添加分账接收方 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_3&index=4
+type ProfitSharingAddReceiver struct {
+	profitSharingParam
+	Receiver ProfitSharingReceiver `xml:"receiver" json:"receiver"` // 分账接收方
+}
+
+func (p ProfitSharingAddReceiver) ApiPath() string {
+	return "/pay/profitsharingaddreceiver"
+}
+
+func (p ProfitSharingAddReceiver) Validate() error {
+	var v validator
+	validateReceiver(&v, "receiver", p.Receiver.Type, p.Receiver.Account)
+	if p.Receiver.Type == ReceiverTypeMerchantId {
+		v.required("receiver.name", p.Receiver.Name)
+	}
+	if v.required("receiver.relation_type", p.Receiver.RelationType) && p.Receiver.RelationType == RelationTypeCustom {
+		v.required("receiver.custom_relation", p.Receiver.CustomRelation)
+	}
+	return v.err()
+}
+
+// ProfitSharingReceiverRsp 添加、删除分账接收方响应参数
+type ProfitSharingReceiverRsp struct {
+	PayError
+	MchId    string `xml:"mch_id" json:"mch_id"`     // 微信支付分配的商户号
+	AppId    string `xml:"appid" json:"appid"`       // 微信分配的公众账号ID
+	Receiver string `xml:"receiver" json:"receiver"` // 分账接收方，JSON格式
+}
+
+/* 删除分账接收方 */
+
+// ProfitSharingRemoveReceiver 删除分账接收方 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_4&index=5
+type ProfitSharingRemoveReceiver struct {
+	profitSharingParam
+	Receiver ProfitSharingReceiver `xml:"receiver" json:"receiver"` // 分账接收方，只需 type、account
+}
+
+func (p ProfitSharingRemoveReceiver) ApiPath() string {
+	return "/pay/profitsharingremovereceiver"
+}
+
+func (p ProfitSharingRemoveReceiver) Validate() error {
+	var v validator
+	validateReceiver(&v, "receiver", p.Receiver.Type, p.Receiver.Account)
+	return v.err()
+}
+
+/* 请求分账 */
+
+// ProfitSharing 请求单次分账 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_1&index=1
+// 设置 Multi 为 true 时请求多次分账 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_6&index=2
+type ProfitSharing struct {
+	profitSharingParam
+	Multi         bool                   `xml:"-" json:"-"`                           // 是否多次分账，单次分账请求后剩余资金自动解冻给本商户，多次分账需调用完结分账
+	TransactionId string                 `xml:"transaction_id" json:"transaction_id"` // 微信支付订单号
+	OutOrderNo    string                 `xml:"out_order_no" json:"out_order_no"`     // 商户系统内部的分账单号，只能是数字、大小写字母_-|*@
+	Receivers     ProfitSharingReceivers `xml:"receivers" json:"receivers"`           // 分账接收方列表，不超过50个
+}
+
+func (p ProfitSharing) NeedTlsCert() bool {
+	return true
+}
+
+func (p ProfitSharing) ApiPath() string {
+	if p.Multi {
+		return "/secapi/pay/multiprofitsharing"
+	}
+	return "/secapi/pay/profitsharing"
+}
+
+func (p ProfitSharing) Validate() error {
+	var v validator
+	v.required("transaction_id", p.TransactionId)
+	v.outNo("out_order_no", p.OutOrderNo)
+	p.Receivers.validate(&v)
+	return v.err()
+}
+
+// ProfitSharingRsp 请求分账、完结分账响应参数
+type ProfitSharingRsp struct {
+	PayError
+	MchId         string `xml:"mch_id" json:"mch_id"`                 // 微信支付分配的商户号
+	AppId         string `xml:"appid" json:"appid"`                   // 微信分配的公众账号ID
+	TransactionId string `xml:"transaction_id" json:"transaction_id"` // 微信支付订单号
+	OutOrderNo    string `xml:"out_order_no" json:"out_order_no"`     // 调用接口提供的商户系统内部的分账单号
+	OrderId       string `xml:"order_id" json:"order_id"`             // 微信分账单号，微信系统返回的唯一标识
+	Status        string `xml:"status" json:"status"`                 // 分账单状态，PROCESSING、FINISHED
+}
+
+/* 查询分账结果 */
+
+// ProfitSharingQuery 查询分账结果 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_2&index=3
+type ProfitSharingQuery struct {
+	profitSharingParam
+	TransactionId string `xml:"transaction_id" json:"transaction_id"` // 微信支付订单号
+	OutOrderNo    string `xml:"out_order_no" json:"out_order_no"`     // 查询分账结果，输入申请分账时的商户分账单号
+}
+
+// 查询分账结果不需要appid
+func (p ProfitSharingQuery) NeedAppId() bool {
+	return false
+}
+
+func (p ProfitSharingQuery) ApiPath() string {
+	return "/pay/profitsharingquery"
+}
+
+func (p ProfitSharingQuery) Validate() error {
+	var v validator
+	v.required("transaction_id", p.TransactionId)
+	v.outNo("out_order_no", p.OutOrderNo)
+	return v.err()
+}
+
+// ProfitSharingQueryRsp 查询分账结果响应参数
+type ProfitSharingQueryRsp struct {
+	PayError
+	MchId         string                 `xml:"mch_id" json:"mch_id"`                 // 微信支付分配的商户号
+	TransactionId string                 `xml:"transaction_id" json:"transaction_id"` // 微信支付订单号
+	OutOrderNo    string                 `xml:"out_order_no" json:"out_order_no"`     // 调用接口提供的商户系统内部的分账单号
+	OrderId       string                 `xml:"order_id" json:"order_id"`             // 微信分账单号
+	Status        string                 `xml:"status" json:"status"`                 // 分账单状态，ACCEPTED、PROCESSING、FINISHED、CLOSED
+	CloseReason   string                 `xml:"close_reason" json:"close_reason"`     // 关单原因，NO_AUTH:分账授权已解除
+	Receivers     ProfitSharingReceivers `xml:"receivers" json:"receivers"`           // 分账接收方列表及分账结果
+	Amount        Fen                    `xml:"amount" json:"amount"`                 // 分账完结的金额，单位为分，仅完结分账时返回
+	Description   string                 `xml:"description" json:"description"`       // 分账完结的原因描述，仅完结分账时返回
+}
+
+/* 分账回退 */
+
+// ProfitSharingReturn 分账回退 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_7&index=7
+type ProfitSharingReturn struct {
+	profitSharingParam
+	OrderId           string `xml:"order_id,omitempty" json:"order_id,omitempty"`         // 原发起分账请求时，微信返回的微信分账单号，与商户分账单号二选一
+	OutOrderNo        string `xml:"out_order_no,omitempty" json:"out_order_no,omitempty"` // 原发起分账请求时使用的商户系统内部的分账单号，与微信分账单号二选一
+	OutReturnNo       string `xml:"out_return_no" json:"out_return_no"`                   // 商户系统内部的回退单号，只能是数字、大小写字母_-|*@
+	ReturnAccountType string `xml:"return_account_type" json:"return_account_type"`       // 回退方类型，MERCHANT_ID，为空时自动填充
+	ReturnAccount     string `xml:"return_account" json:"return_account"`                 // 回退方账号，回退方类型是MERCHANT_ID时，填写商户ID
+	ReturnAmount      Fen    `xml:"return_amount" json:"return_amount"`                   // 需要从分账接收方回退的金额，单位为分
+	Description       string `xml:"description" json:"description"`                       // 分账回退的原因描述
+}
+
+func (p ProfitSharingReturn) NeedTlsCert() bool {
+	return true
+}
+
+func (p ProfitSharingReturn) ApiPath() string {
+	return "/secapi/pay/profitsharingreturn"
+}
+
+func (p ProfitSharingReturn) Validate() error {
+	var v validator
+	if p.OrderId == "" && p.OutOrderNo == "" {
+		v.add("order_id", "order_id or out_order_no is required")
+	}
+	v.outNo("out_return_no", p.OutReturnNo)
+	if p.ReturnAccountType != kReturnAccountTypeMerchantId {
+		v.add("return_account_type", "must be MERCHANT_ID")
+	}
+	v.required("return_account", p.ReturnAccount)
+	v.amount("return_amount", p.ReturnAmount)
+	if v.required("description", p.Description) {
+		v.maxLen("description", p.Description, kProfitSharingDescriptionLimit)
+	}
+	return v.err()
+}
+
+/* 回退结果查询 */
+
+// ProfitSharingReturnQuery 分账回退结果查询 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_8&index=8
+type ProfitSharingReturnQuery struct {
+	profitSharingParam
+	OrderId     string `xml:"order_id,omitempty" json:"order_id,omitempty"`         // 原发起分账请求时，微信返回的微信分账单号，与商户分账单号二选一
+	OutOrderNo  string `xml:"out_order_no,omitempty" json:"out_order_no,omitempty"` // 原发起分账请求时使用的商户系统内部的分账单号，与微信分账单号二选一
+	OutReturnNo string `xml:"out_return_no" json:"out_return_no"`                   // 调用回退接口提供的商户系统内部的回退单号
+}
+
+func (p ProfitSharingReturnQuery) ApiPath() string {
+	return "/pay/profitsharingreturnquery"
+}
+
+func (p ProfitSharingReturnQuery) Validate() error {
+	var v validator
+	if p.OrderId == "" && p.OutOrderNo == "" {
+		v.add("order_id", "order_id or out_order_no is required")
+	}
+	v.outNo("out_return_no", p.OutReturnNo)
+	return v.err()
+}
+
+// ProfitSharingReturnRsp 分账回退、回退结果查询响应参数，result 为 PROCESSING 时需查询回退结果
+type ProfitSharingReturnRsp struct {
+	PayError
+	MchId             string `xml:"mch_id" json:"mch_id"`                           // 微信支付分配的商户号
+	AppId             string `xml:"appid" json:"appid"`                             // 微信分配的公众账号ID
+	OrderId           string `xml:"order_id" json:"order_id"`                       // 原发起分账请求时，微信返回的微信分账单号
+	OutOrderNo        string `xml:"out_order_no" json:"out_order_no"`               // 原发起分账请求时使用的商户系统内部的分账单号
+	OutReturnNo       string `xml:"out_return_no" json:"out_return_no"`             // 商户系统内部的回退单号
+	ReturnNo          string `xml:"return_no" json:"return_no"`                     // 微信分账回退单号，微信系统返回的唯一标识
+	ReturnAccountType string `xml:"return_account_type" json:"return_account_type"` // 回退方类型
+	ReturnAccount     string `xml:"return_account" json:"return_account"`           // 回退方账号
+	ReturnAmount      Fen    `xml:"return_amount" json:"return_amount"`             // 回退金额，单位为分
+	Description       string `xml:"description" json:"description"`                 // 分账回退的原因描述
+	Result            string `xml:"result" json:"result"`                           // 回退结果，PROCESSING、SUCCESS、FAILED
+	FailReason        string `xml:"fail_reason" json:"fail_reason"`                 // 失败原因，ACCOUNT_ABNORMAL、TIME_OUT_CLOSED、PAYER_ACCOUNT_ABNORMAL
+	FinishTime        string `xml:"finish_time" json:"finish_time"`                 // 分账回退完成时间
+}
+
+/* 完结分账 */
+
+// ProfitSharingFinish 完结分账 https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_5&index=6
+type ProfitSharingFinish struct {
+	profitSharingParam
+	TransactionId string `xml:"transaction_id" json:"transaction_id"` // 微信支付订单号
+	OutOrderNo    string `xml:"out_order_no" json:"out_order_no"`     // 商户系统内部的分账单号
+	Amount        Fen    `xml:"amount" json:"amount"`                 // 分账完结的金额，单位为分，只能为0
+	Description   string `xml:"description" json:"description"`       // 分账完结的原因描述
+}
+
+func (p ProfitSharingFinish) NeedTlsCert() bool {
+	return true
+}
+
+func (p ProfitSharingFinish) ApiPath() string {
+	return "/secapi/pay/profitsharingfinish"
+}
+
+func (p ProfitSharingFinish) Validate() error {
+	var v validator
+	v.required("transaction_id", p.TransactionId)
+	v.outNo("out_order_no", p.OutOrderNo)
+	if p.Amount != 0 {
+		v.add("amount", "must be 0")
+	}
+	if v.required("description", p.Description) {
+		v.maxLen("description", p.Description, kProfitSharingDescriptionLimit)
+	}
+	return v.err()
+}
@@ -1,5 +1,7 @@
 package wxpay
 
+import "strings"
+
 type Qrcode struct {
 	AuxParam
 }
@@ -20,6 +22,10 @@ func (g Qrcode) ReturnType() string {
 	return "byte"
 }
 
+func (g Qrcode) NeedAccessToken() bool {
+	return true
+}
+
 // GetWxACodeUnLimit 获取不限制的小程序码 https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/qrcode-link/qr-code/getUnlimitedQRCode.html
 // 该接口用于获取小程序码，适用于需要的码数量极多的业务场景。通过该接口生成的小程序码，永久有效，数量暂无限制。
 type GetWxACodeUnLimit struct {
@@ -34,6 +40,22 @@ type GetWxACodeUnLimit struct {
 	IsHyaline  bool      `json:"is_hyaline"`           // 默认是false，是否需要透明底色，为 true 时，生成透明底色的小程序
 }
 
+func (g GetWxACodeUnLimit) ApiPath() string {
+	return "/wxa/getwxacodeunlimit"
+}
+
+func (g GetWxACodeUnLimit) Validate() error {
+	var v validator
+	if v.required("scene", g.Scene) {
+		v.maxLen("scene", g.Scene, 32)
+	}
+	if strings.HasPrefix(g.Page, "/") {
+		v.add("page", "must not start with /")
+	}
+	v.qrcodeWidth(g.Width)
+	return v.err()
+}
+
 type LineColor struct {
 	R int `json:"r"`
 	G int `json:"g"`
@@ -48,6 +70,19 @@ type CreateQRCode struct {
 	Width int    `json:"width"` // 二维码的宽度，单位 px。最小 280px，最大 1280px;默认是430
 }
 
+func (g CreateQRCode) ApiPath() string {
+	return "/cgi-bin/wxaapp/createwxaqrcode"
+}
+
+func (g CreateQRCode) Validate() error {
+	var v validator
+	if v.required("path", g.Path) {
+		v.maxLen("path", g.Path, 128)
+	}
+	v.qrcodeWidth(g.Width)
+	return v.err()
+}
+
 // GetQRCode 获取小程序码 https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/qrcode-link/qr-code/getQRCode.html
 // 该接口用于获取小程序码，适用于需要的码数量较少的业务场景。通过该接口生成的小程序码，永久有效，有数量限制。
 type GetQRCode struct {
@@ -60,6 +95,19 @@ type GetQRCode struct {
 	EnvVersion string    `json:"env_version"`          // 要打开的小程序版本。正式版为 "release"，体验版为 "trial"，开发版为 "develop"。默认是正式版。
 }
 
+func (g GetQRCode) ApiPath() string {
+	return "/wxa/getwxacode"
+}
+
+func (g GetQRCode) Validate() error {
+	var v validator
+	if v.required("path", g.Path) {
+		v.maxLen("path", g.Path, 1024)
+	}
+	v.qrcodeWidth(g.Width)
+	return v.err()
+}
+
 type QrcodeRsp struct {
 	AppletError
 	Buffer []byte `json:"buffer"` // 二维码二进制
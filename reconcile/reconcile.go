@@ -0,0 +1,180 @@
+// Package reconcile 对账，比较本地订单与微信交易账单（或查询订单结果），找出差异
+package reconcile
+
+import (
+	"fmt"
+	"sort"
+
+	wxpay "github.com/Caiqm/wxpay-v2"
+)
+
+// Order 参与对账的订单，OutRefundNo 不为空时表示一笔退款，状态为 wxpay.TradeStateRefund
+type Order struct {
+	OutTradeNo    string    `json:"out_trade_no"`             // 商户订单号
+	OutRefundNo   string    `json:"out_refund_no,omitempty"`  // 商户退款单号，退款按退款单号对账
+	TransactionId string    `json:"transaction_id,omitempty"` // 微信订单号
+	Amount        wxpay.Fen `json:"amount"`                   // 订单金额，退款时为申请退款金额，单位为分
+	Status        string    `json:"status"`                   // 订单状态，使用微信交易状态，如 wxpay.TradeStateSuccess、wxpay.TradeStateRefund
+}
+
+// 订单是否已支付，已支付的订单应出现在微信账单中
+func (o Order) paid() bool {
+	return o.Status == wxpay.TradeStateSuccess || o.Status == wxpay.TradeStateRefund
+}
+
+// Kind 差异类型
+type Kind string
+
+const (
+	MissingLocally  Kind = "MISSING_LOCALLY"  // 微信有记录，本地没有
+	MissingRemotely Kind = "MISSING_REMOTELY" // 本地已支付，微信没有记录
+	AmountMismatch  Kind = "AMOUNT_MISMATCH"  // 金额不一致
+	StatusMismatch  Kind = "STATUS_MISMATCH"  // 状态不一致，如本地为 REFUND，微信为 SUCCESS
+)
+
+// Diff 对账差异
+type Diff struct {
+	Kind        Kind   `json:"kind"`
+	OutTradeNo  string `json:"out_trade_no"`
+	OutRefundNo string `json:"out_refund_no,omitempty"` // 退款差异时为商户退款单号
+	Local       *Order `json:"local,omitempty"`         // 本地订单，MissingLocally 时为空
+	Remote      *Order `json:"remote,omitempty"`        // 微信订单，MissingRemotely 时为空
+}
+
+// Report 对账结果
+type Report struct {
+	LocalCount   int    `json:"local_count"`   // 本地订单数，含退款
+	RemoteCount  int    `json:"remote_count"`  // 微信订单数，同一订单的支付与退款记录合并计算，支付不在本账单中的退款单独计算
+	MatchedCount int    `json:"matched_count"` // 一致的订单数
+	Diffs        []Diff `json:"diffs"`         // 差异，按商户订单号、商户退款单号排序
+}
+
+// Reconcile 对账，local 为本地订单，remote 为微信订单（FromBill 或 FromOrderQueries）
+//
+// 微信账单中同一订单的支付与退款记录会合并，存在退款记录时状态为 REFUND；
+// 本地未支付（非 SUCCESS、REFUND）且微信没有记录的订单不视为差异。
+// 退款按商户退款单号与本地退款（OutRefundNo 不为空的订单）对账，支付在更早账单中的退款（如今天退款昨天的订单）
+// 没有对应的本地退款时视为 MissingLocally，支付在同一账单中的退款已体现在订单状态中，本地可不提供。
+//
+// 两边订单均按商户订单号、商户退款单号保存在内存中，占用内存与订单数成正比，订单量很大时应按账单日分批对账
+func Reconcile(local, remote Source) (*Report, error) {
+	locals := make(map[string]Order)
+	localRefunds := make(map[string]Order)
+	for local.Next() {
+		o := local.Order()
+		key, m := o.OutTradeNo, locals
+		if o.OutRefundNo != "" {
+			key, m = o.OutRefundNo, localRefunds
+		}
+		if _, ok := m[key]; ok {
+			return nil, fmt.Errorf("reconcile: duplicate local order %s", key)
+		}
+		m[key] = o
+	}
+	if err := local.Err(); err != nil {
+		return nil, err
+	}
+	remotes := make(map[string]Order)
+	remoteRefunds := make(map[string]Order)
+	for remote.Next() {
+		o := remote.Order()
+		if o.OutRefundNo != "" {
+			remoteRefunds[o.OutRefundNo] = o
+			continue
+		}
+		if exist, ok := remotes[o.OutTradeNo]; ok {
+			o = mergeRemote(exist, o)
+		}
+		remotes[o.OutTradeNo] = o
+	}
+	if err := remote.Err(); err != nil {
+		return nil, err
+	}
+	// 支付在同一账单中的退款合并到订单，其余退款单独对账
+	for outRefundNo, r := range remoteRefunds {
+		if exist, ok := remotes[r.OutTradeNo]; ok {
+			exist.Status = wxpay.TradeStateRefund
+			remotes[r.OutTradeNo] = exist
+			if _, ok = localRefunds[outRefundNo]; !ok {
+				delete(remoteRefunds, outRefundNo)
+			}
+		}
+	}
+
+	report := &Report{LocalCount: len(locals) + len(localRefunds), RemoteCount: len(remotes), Diffs: []Diff{}}
+	for outTradeNo, l := range locals {
+		report.compare(outTradeNo, "", l, remotes)
+	}
+	for outTradeNo, r := range remotes {
+		r := r
+		if _, ok := locals[outTradeNo]; !ok {
+			report.Diffs = append(report.Diffs, Diff{Kind: MissingLocally, OutTradeNo: outTradeNo, Remote: &r})
+		}
+	}
+	for outRefundNo, l := range localRefunds {
+		report.compare(l.OutTradeNo, outRefundNo, l, remoteRefunds)
+	}
+	for outRefundNo, r := range remoteRefunds {
+		r := r
+		if _, ok := remotes[r.OutTradeNo]; !ok {
+			report.RemoteCount++
+		}
+		if _, ok := localRefunds[outRefundNo]; !ok {
+			report.Diffs = append(report.Diffs, Diff{Kind: MissingLocally, OutTradeNo: r.OutTradeNo, OutRefundNo: outRefundNo, Remote: &r})
+		}
+	}
+	sort.Slice(report.Diffs, func(i, j int) bool {
+		a, b := report.Diffs[i], report.Diffs[j]
+		if a.OutTradeNo != b.OutTradeNo {
+			return a.OutTradeNo < b.OutTradeNo
+		}
+		if a.OutRefundNo != b.OutRefundNo {
+			return a.OutRefundNo < b.OutRefundNo
+		}
+		return a.Kind < b.Kind
+	})
+	return report, nil
+}
+
+// 比较本地订单与 remotes 中 key 对应的微信订单，key 为商户退款单号或商户订单号
+func (report *Report) compare(outTradeNo, outRefundNo string, l Order, remotes map[string]Order) {
+	key := outTradeNo
+	if outRefundNo != "" {
+		key = outRefundNo
+	}
+	r, ok := remotes[key]
+	if !ok {
+		if outRefundNo != "" || l.paid() {
+			report.Diffs = append(report.Diffs, Diff{Kind: MissingRemotely, OutTradeNo: outTradeNo, OutRefundNo: outRefundNo, Local: &l})
+		} else {
+			report.MatchedCount++
+		}
+		return
+	}
+	matched := true
+	if l.Amount != r.Amount {
+		matched = false
+		report.Diffs = append(report.Diffs, Diff{Kind: AmountMismatch, OutTradeNo: outTradeNo, OutRefundNo: outRefundNo, Local: &l, Remote: &r})
+	}
+	if l.Status != r.Status {
+		matched = false
+		report.Diffs = append(report.Diffs, Diff{Kind: StatusMismatch, OutTradeNo: outTradeNo, OutRefundNo: outRefundNo, Local: &l, Remote: &r})
+	}
+	if matched {
+		report.MatchedCount++
+	}
+}
+
+// 合并同一订单的多条微信记录，退款记录优先
+func mergeRemote(exist, o Order) Order {
+	if exist.Status == wxpay.TradeStateRefund {
+		o.Status = wxpay.TradeStateRefund
+	}
+	if o.TransactionId == "" {
+		o.TransactionId = exist.TransactionId
+	}
+	if o.Amount == 0 {
+		o.Amount = exist.Amount
+	}
+	return o
+}
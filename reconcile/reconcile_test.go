@@ -0,0 +1,147 @@
+package reconcile
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	wxpay "github.com/Caiqm/wxpay-v2"
+)
+
+const testBill = "交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,微信退款单号,商户退款单号,退款金额,充值券退款金额,退款类型,退款状态,商品名称,商户数据包,手续费,费率,订单金额,申请退款金额,费率备注\r\n" +
+	"`2023-11-27 10:00:00,`wx8888888888888888,`1900000109,`0,`,`4200000001,`ORDER000001,`o1,`JSAPI,`SUCCESS,`OTHERS,`CNY,`0.01,`0.00,`0,`0,`0.00,`0.00,`,`,`商品,`,`0.00000,`0.60%,`0.01,`0.00,`\r\n" +
+	"`2023-11-27 10:01:00,`wx8888888888888888,`1900000109,`0,`,`4200000002,`ORDER000002,`o2,`JSAPI,`SUCCESS,`OTHERS,`CNY,`1.00,`0.00,`0,`0,`0.00,`0.00,`,`,`商品,`,`0.00600,`0.60%,`1.00,`0.00,`\r\n" +
+	"`2023-11-27 10:02:00,`wx8888888888888888,`1900000109,`0,`,`4200000002,`ORDER000002,`o2,`JSAPI,`REFUND,`OTHERS,`CNY,`1.00,`0.00,`5000000002,`R000002,`1.00,`0.00,`ORIGINAL,`SUCCESS,`商品,`,`-0.00600,`0.60%,`1.00,`1.00,`\r\n" +
+	"`2023-11-27 10:03:00,`wx8888888888888888,`1900000109,`0,`,`4200000003,`ORDER000003,`o3,`JSAPI,`SUCCESS,`OTHERS,`CNY,`2.00,`0.00,`0,`0,`0.00,`0.00,`,`,`商品,`,`0.01200,`0.60%,`2.00,`0.00,`\r\n" +
+	"`2023-11-27 10:04:00,`wx8888888888888888,`1900000109,`0,`,`4200000005,`ORDER000005,`o5,`JSAPI,`SUCCESS,`OTHERS,`CNY,`5.00,`0.00,`0,`0,`0.00,`0.00,`,`,`商品,`,`0.03000,`0.60%,`5.00,`0.00,`\r\n" +
+	"总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\r\n" +
+	"`5,`9.01,`1.00,`0.00,`0.04800,`9.01,`1.00\r\n"
+
+func testLocalOrders() []Order {
+	return []Order{
+		{OutTradeNo: "ORDER000001", Amount: 1, Status: wxpay.TradeStateSuccess},
+		{OutTradeNo: "ORDER000002", Amount: 100, Status: wxpay.TradeStateSuccess},
+		{OutTradeNo: "ORDER000004", Amount: 400, Status: wxpay.TradeStateSuccess},
+		{OutTradeNo: "ORDER000005", Amount: 600, Status: wxpay.TradeStateSuccess},
+		{OutTradeNo: "ORDER000006", Amount: 600, Status: wxpay.TradeStateNotPay},
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	report, err := Reconcile(Orders(testLocalOrders()), FromBill(wxpay.NewBillReader(strings.NewReader(testBill))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.LocalCount != 5 || report.RemoteCount != 4 || report.MatchedCount != 2 {
+		t.Fatalf("unexpected counts %+v", report)
+	}
+	want := []struct {
+		kind       Kind
+		outTradeNo string
+	}{
+		{StatusMismatch, "ORDER000002"},
+		{MissingLocally, "ORDER000003"},
+		{MissingRemotely, "ORDER000004"},
+		{AmountMismatch, "ORDER000005"},
+	}
+	if len(report.Diffs) != len(want) {
+		t.Fatalf("expected %d diffs, got %+v", len(want), report.Diffs)
+	}
+	for i, w := range want {
+		if d := report.Diffs[i]; d.Kind != w.kind || d.OutTradeNo != w.outTradeNo {
+			t.Errorf("diff %d: expected %s %s, got %s %s", i, w.kind, w.outTradeNo, d.Kind, d.OutTradeNo)
+		}
+	}
+	if r := report.Diffs[0].Remote; r.Status != wxpay.TradeStateRefund || r.TransactionId != "4200000002" {
+		t.Fatalf("expected merged refund record, got %+v", r)
+	}
+}
+
+// 支付在更早账单中的退款按商户退款单号对账
+func TestReconcile_Refund(t *testing.T) {
+	bill := strings.Replace(testBill, "`2023-11-27 10:02:00,`wx8888888888888888,`1900000109,`0,`,`4200000002,`ORDER000002", "`2023-11-27 10:02:00,`wx8888888888888888,`1900000109,`0,`,`4200000000,`ORDER000000", 1)
+	bill = strings.Replace(bill, "`R000002,", "`R000000,", 1)
+	local := []Order{
+		{OutTradeNo: "ORDER000000", OutRefundNo: "R000000", Amount: 100, Status: wxpay.TradeStateRefund},
+		{OutTradeNo: "ORDER000001", Amount: 1, Status: wxpay.TradeStateSuccess},
+		{OutTradeNo: "ORDER000001", OutRefundNo: "R000001", Amount: 1, Status: wxpay.TradeStateRefund},
+	}
+	report, err := Reconcile(Orders(local), FromBill(wxpay.NewBillReader(strings.NewReader(bill))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.LocalCount != 3 || report.RemoteCount != 5 || report.MatchedCount != 2 {
+		t.Fatalf("unexpected counts %+v", report)
+	}
+	for _, d := range report.Diffs {
+		if d.OutTradeNo == "ORDER000000" {
+			t.Fatalf("unexpected refund diff %+v", d)
+		}
+	}
+	if d := report.Diffs[0]; d.Kind != MissingRemotely || d.OutRefundNo != "R000001" {
+		t.Fatalf("expected missing remote refund, got %+v", d)
+	}
+
+	// 没有本地退款时视为 MissingLocally
+	if report, err = Reconcile(Orders(local[1:2]), FromBill(wxpay.NewBillReader(strings.NewReader(bill)))); err != nil {
+		t.Fatal(err)
+	}
+	if d := report.Diffs[0]; d.Kind != MissingLocally || d.OutTradeNo != "ORDER000000" || d.OutRefundNo != "R000000" || d.Remote.Amount != 100 {
+		t.Fatalf("expected missing local refund, got %+v", d)
+	}
+}
+
+func TestReconcile_OrderQueries(t *testing.T) {
+	var paid, notExist wxpay.TradeOrderQueryRsp
+	paid.ResultCode = "SUCCESS"
+	paid.OutTradeNo = "ORDER000001"
+	paid.TradeState = wxpay.TradeStateSuccess
+	paid.TotalFee = 1
+	notExist.ResultCode = "FAIL"
+	notExist.ErrCode = wxpay.PayErrCodeOrderNotExist
+	local := []Order{
+		{OutTradeNo: "ORDER000001", Amount: 1, Status: wxpay.TradeStateSuccess},
+		{OutTradeNo: "ORDER000002", Amount: 1, Status: wxpay.TradeStateSuccess},
+	}
+	report, err := Reconcile(Orders(local), FromOrderQueries([]*wxpay.TradeOrderQueryRsp{&paid, &notExist}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.MatchedCount != 1 || len(report.Diffs) != 1 || report.Diffs[0].Kind != MissingRemotely {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestReconcile_DuplicateLocal(t *testing.T) {
+	local := []Order{{OutTradeNo: "ORDER000001"}, {OutTradeNo: "ORDER000001"}}
+	if _, err := Reconcile(Orders(local), Orders(nil)); err == nil {
+		t.Fatal("expected duplicate error")
+	}
+}
+
+func TestReport_Write(t *testing.T) {
+	report, err := Reconcile(Orders(testLocalOrders()), FromBill(wxpay.NewBillReader(strings.NewReader(testBill))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = report.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || lines[4] != "AMOUNT_MISMATCH,ORDER000005,,4200000005,6.00,5.00,SUCCESS,SUCCESS" {
+		t.Fatalf("unexpected csv %q", buf.String())
+	}
+	buf.Reset()
+	if err = report.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err = json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Diffs) != 4 || decoded.Diffs[3].Local.Amount != 600 {
+		t.Fatalf("unexpected json %s", buf.String())
+	}
+}
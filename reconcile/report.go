@@ -0,0 +1,41 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// WriteJSON 以JSON格式输出对账结果
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV 以CSV格式输出对账差异，金额单位为元
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"kind", "out_trade_no", "out_refund_no", "transaction_id", "local_amount", "remote_amount", "local_status", "remote_status"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, d := range r.Diffs {
+		row := []string{string(d.Kind), d.OutTradeNo, d.OutRefundNo, "", "", "", "", ""}
+		if d.Local != nil {
+			row[3] = d.Local.TransactionId
+			row[4] = d.Local.Amount.Yuan()
+			row[6] = d.Local.Status
+		}
+		if d.Remote != nil {
+			row[3] = d.Remote.TransactionId
+			row[5] = d.Remote.Amount.Yuan()
+			row[7] = d.Remote.Status
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,106 @@
+package reconcile
+
+import (
+	"context"
+
+	wxpay "github.com/Caiqm/wxpay-v2"
+)
+
+// Source 订单流，用法与 wxpay.BillReader 一致
+type Source interface {
+	// Next 读取下一笔订单，没有更多订单或出错时返回 false
+	Next() bool
+
+	// Order 当前订单
+	Order() Order
+
+	// Err 读取过程中的错误
+	Err() error
+}
+
+// Orders 使用订单列表作为订单流，一般用于本地订单
+func Orders(orders []Order) Source {
+	return &sliceSource{orders: orders, index: -1}
+}
+
+type sliceSource struct {
+	orders []Order
+	index  int
+}
+
+func (s *sliceSource) Next() bool {
+	s.index++
+	return s.index < len(s.orders)
+}
+
+func (s *sliceSource) Order() Order {
+	return s.orders[s.index]
+}
+
+func (s *sliceSource) Err() error {
+	return nil
+}
+
+// FromBill 使用交易账单作为微信订单流，退款记录的交易状态为 REFUND，金额为申请退款金额
+func FromBill(r *wxpay.BillReader) Source {
+	return &billSource{r: r}
+}
+
+type billSource struct {
+	r *wxpay.BillReader
+}
+
+func (s *billSource) Next() bool {
+	return s.r.Next()
+}
+
+func (s *billSource) Order() Order {
+	record := s.r.Record()
+	o := Order{
+		OutTradeNo:    record.OutTradeNo,
+		TransactionId: record.TransactionId,
+		Amount:        record.TotalFee,
+		Status:        record.TradeState,
+	}
+	if record.TradeState == wxpay.TradeStateRefund {
+		o.OutRefundNo = record.OutRefundNo
+		o.Amount = record.RefundFee
+	}
+	return o
+}
+
+func (s *billSource) Err() error {
+	return s.r.Err()
+}
+
+// FromOrderQueries 使用查询订单结果作为微信订单流，业务结果失败（如订单不存在）的结果会被忽略
+func FromOrderQueries(results []*wxpay.TradeOrderQueryRsp) Source {
+	orders := make([]Order, 0, len(results))
+	for _, rsp := range results {
+		if rsp == nil || rsp.ResultCode != string(wxpay.ReturnCodeSuccess) {
+			continue
+		}
+		orders = append(orders, Order{
+			OutTradeNo:    rsp.OutTradeNo,
+			TransactionId: rsp.TransactionId,
+			Amount:        rsp.TotalFee,
+			Status:        rsp.TradeState,
+		})
+	}
+	return Orders(orders)
+}
+
+// QueryOrders 按商户订单号逐笔查询订单，结果可通过 FromOrderQueries 转换为订单流
+func QueryOrders(ctx context.Context, client *wxpay.Client, outTradeNos []string) ([]*wxpay.TradeOrderQueryRsp, error) {
+	results := make([]*wxpay.TradeOrderQueryRsp, 0, len(outTradeNos))
+	for _, outTradeNo := range outTradeNos {
+		var p wxpay.TradeOrderQuery
+		p.OutTradeNo = outTradeNo
+		rsp, err := client.TradeOrderQuery(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, rsp)
+	}
+	return results, nil
+}
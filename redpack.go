@@ -0,0 +1,68 @@
+package wxpay
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// SendRedPack 发放普通红包 https://pay.weixin.qq.com/wiki/doc/api/tools/cash_coupon.php?chapter=13_4&index=3
+// POST https://api.mch.weixin.qq.com/mmpaymkttransfers/sendredpack
+// 需要加载证书，total_num 为空时自动填充为1，返回 SYSTEMERROR 等结果未知的错误时，需使用原商户订单号重试
+func (c *Client) SendRedPack(ctx context.Context, param SendRedPack) (result *RedPackRsp, err error) {
+	if param.TotalNum == 0 {
+		param.TotalNum = 1
+	}
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
+
+// SendGroupRedPack 发放裂变红包 https://pay.weixin.qq.com/wiki/doc/api/tools/cash_coupon.php?chapter=13_5&index=4
+// POST https://api.mch.weixin.qq.com/mmpaymkttransfers/sendgroupredpack
+// 需要加载证书，amt_type 为空时自动填充为 ALL_RAND
+func (c *Client) SendGroupRedPack(ctx context.Context, param SendGroupRedPack) (result *RedPackRsp, err error) {
+	if param.AmtType == "" {
+		param.AmtType = kRedPackAmtTypeAllRand
+	}
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
+
+// GetHbInfo 查询红包记录 https://pay.weixin.qq.com/wiki/doc/api/tools/cash_coupon.php?chapter=13_6&index=5
+// POST https://api.mch.weixin.qq.com/mmpaymkttransfers/gethbinfo
+// 需要加载证书，bill_type 为空时自动填充为 MCHT
+func (c *Client) GetHbInfo(ctx context.Context, param GetHbInfo) (result *GetHbInfoRsp, err error) {
+	if param.BillType == "" {
+		param.BillType = kRedPackBillTypeMCHT
+	}
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
+
+// SendMiniProgramRedPack 发放小程序红包 https://pay.weixin.qq.com/wiki/doc/api/tools/cash_coupon.php?chapter=18_2&index=3
+// POST https://api.mch.weixin.qq.com/mmpaymkttransfers/sendminiprogramhb
+// 需要加载证书，返回小程序调用 wx.sendBizRedPacket 所需的参数，业务结果失败时返回 PayError
+func (c *Client) SendMiniProgramRedPack(ctx context.Context, param SendMiniProgramRedPack) (result MiniProgramRedPackRsp, err error) {
+	if param.TotalNum == 0 {
+		param.TotalNum = 1
+	}
+	if param.NotifyWay == "" {
+		param.NotifyWay = kRedPackNotifyWayMiniProgram
+	}
+	rsp := new(SendMiniProgramRedPackRsp)
+	if err = c.doRequest(ctx, "POST", param, &rsp); err != nil {
+		return
+	}
+	// 发放失败时没有 package，不能返回签名参数
+	if rsp.ResultCode != string(ReturnCodeSuccess) {
+		err = rsp.PayError
+		return
+	}
+	result.Timestamp = fmt.Sprintf("%d", time.Now().Unix())
+	result.NonceStr = c.createNonceStr()
+	result.Package = url.QueryEscape(rsp.Package)
+	result.SignType = SignTypeMD5
+	result.PaySign = c.createRedPackPaySign(result.Timestamp, result.NonceStr, result.Package)
+	return
+}
@@ -0,0 +1,212 @@
+package wxpay
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testHbInfoRsp = `<xml>
+<return_code><![CDATA[SUCCESS]]></return_code>
+<return_msg><![CDATA[OK]]></return_msg>
+<result_code><![CDATA[SUCCESS]]></result_code>
+<mch_billno><![CDATA[1234567890202311270000000001]]></mch_billno>
+<mch_id><![CDATA[` + testMchId + `]]></mch_id>
+<detail_id><![CDATA[1000041701201411111234567890]]></detail_id>
+<status><![CDATA[RECEIVED]]></status>
+<send_type><![CDATA[API]]></send_type>
+<hb_type><![CDATA[GROUP]]></hb_type>
+<total_num>3</total_num>
+<total_amount>300</total_amount>
+<send_time><![CDATA[2023-11-27 10:00:00]]></send_time>
+<hblist>
+<hbinfo><openid><![CDATA[oxTWIuGaIt6gTKsQRLau2M0yL16E]]></openid><amount>100</amount><rcv_time><![CDATA[2023-11-27 10:01:00]]></rcv_time></hbinfo>
+<hbinfo><openid><![CDATA[oxTWIuGaIt6gTKsQRLau2M0yL16F]]></openid><amount>200</amount><rcv_time><![CDATA[2023-11-27 10:02:00]]></rcv_time></hbinfo>
+</hblist>
+</xml>`
+
+// 模拟红包接口，要求客户端证书，校验 wxappid 字段与不带 sign_type 的MD5签名
+func newRedPackServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	signer, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fail := func(w http.ResponseWriter, msg string) {
+		b, _ := xml.Marshal(payXml{"return_code": "FAIL", "return_msg": msg})
+		w.Write(b)
+	}
+	send := func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			fail(w, "cert required")
+			return
+		}
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			fail(w, err.Error())
+			return
+		}
+		if m["wxappid"] != testAppId || m[kFieldMchId] != testMchId || m[kFieldAppId] != "" || m[kFieldSignType] != "" {
+			fail(w, "invalid fields")
+			return
+		}
+		if r.URL.Path == "/mmpaymkttransfers/sendgroupredpack" && (m["amt_type"] != kRedPackAmtTypeAllRand || m["client_ip"] != "") {
+			fail(w, "invalid group fields")
+			return
+		}
+		b, _ := xml.Marshal(payXml{
+			"return_code":  "SUCCESS",
+			"result_code":  "SUCCESS",
+			"mch_billno":   m["mch_billno"],
+			"mch_id":       m[kFieldMchId],
+			"wxappid":      m["wxappid"],
+			"re_openid":    m["re_openid"],
+			"total_amount": m["total_amount"],
+			"send_listid":  "100000000020150520314766074200",
+		})
+		w.Write(b)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mmpaymkttransfers/sendredpack", send)
+	mux.HandleFunc("/mmpaymkttransfers/sendgroupredpack", send)
+	mux.HandleFunc("/mmpaymkttransfers/sendminiprogramhb", func(w http.ResponseWriter, r *http.Request) {
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			fail(w, err.Error())
+			return
+		}
+		if m["wxappid"] != testAppId || m["notify_way"] != kRedPackNotifyWayMiniProgram || m["total_num"] != "1" {
+			fail(w, "invalid fields")
+			return
+		}
+		if m["total_amount"] == "100000" {
+			b, _ := xml.Marshal(payXml{"return_code": "SUCCESS", "result_code": "FAIL", "err_code": "NOTENOUGH", "err_code_des": "帐号余额不足，请到商户平台充值后再重试"})
+			w.Write(b)
+			return
+		}
+		b, _ := xml.Marshal(payXml{
+			"return_code": "SUCCESS",
+			"result_code": "SUCCESS",
+			"mch_billno":  m["mch_billno"],
+			"send_listid": "100000000020150520314766074200",
+			"package":     "sendid=242e8abd163d300019b2cae74ba8e8c06e3f0e51ab84d16b3c80decd22a5b672&ver=8&sign=4110d649a5aef52dd6b95654ddf91ca7d5411ac159ace4e1a766b7d3967a1c3dfe1d256811445a4abda2d9cfa4a9b377a829258bd00d90313c6c346f2349fe5d&mchid=11475856&spid=11475856",
+		})
+		w.Write(b)
+	})
+	mux.HandleFunc("/mmpaymkttransfers/gethbinfo", func(w http.ResponseWriter, r *http.Request) {
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			fail(w, err.Error())
+			return
+		}
+		if m[kFieldAppId] != testAppId || m["bill_type"] != kRedPackBillTypeMCHT || m[kFieldSignType] != "" {
+			fail(w, "invalid fields")
+			return
+		}
+		w.Write([]byte(testHbInfoRsp))
+	})
+	server := httptest.NewUnstartedServer(mux)
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testRedPack() RedPack {
+	var p RedPack
+	p.MchBillNo = "1234567890202311270000000001"
+	p.SendName = "天虹百货"
+	p.ReOpenId = "oxTWIuGaIt6gTKsQRLau2M0yL16E"
+	p.TotalAmount = 300
+	p.Wishing = "感谢您参加猜灯谜活动"
+	p.ActName = "猜灯谜抢红包活动"
+	p.Remark = "猜越多得越多，快来抢！"
+	return p
+}
+
+func TestClient_SendRedPack(t *testing.T) {
+	server := newRedPackServer(t)
+	pemCert, keyCert := newTestCert(t)
+	c := newTestClient(t, server, WithTlsCert(pemCert, keyCert), WithSignType(SignTypeHMACSHA256))
+
+	r, err := c.SendRedPack(context.Background(), SendRedPack{RedPack: testRedPack(), ClientIp: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SendListId == "" || r.WxAppId != testAppId || r.TotalAmount != 300 {
+		t.Fatalf("unexpected result %+v", r)
+	}
+
+	group := SendGroupRedPack{RedPack: testRedPack()}
+	group.TotalNum = 3
+	if r, err = c.SendGroupRedPack(context.Background(), group); err != nil {
+		t.Fatal(err)
+	}
+	if r.SendListId == "" {
+		t.Fatalf("unexpected result %+v", r)
+	}
+
+	info, err := c.GetHbInfo(context.Background(), GetHbInfo{MchBillNo: group.MchBillNo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Status != RedPackStatusReceived || info.TotalNum != 3 || len(info.HbList) != 2 {
+		t.Fatalf("unexpected hb info %+v", info)
+	}
+	if info.HbList[1].OpenId != "oxTWIuGaIt6gTKsQRLau2M0yL16F" || info.HbList[1].Amount != 200 {
+		t.Fatalf("unexpected hb list %+v", info.HbList)
+	}
+}
+
+func TestSendRedPack_Validate(t *testing.T) {
+	p := SendGroupRedPack{RedPack: testRedPack(), AmtType: kRedPackAmtTypeAllRand}
+	p.MchBillNo = "12345678902023112700000000011"
+	p.TotalNum = 21
+	fields := invalidFields(t, p.Validate())
+	if len(fields) != 2 || fields[0] != "mch_billno" || fields[1] != "total_num" {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+}
+
+func TestClient_SendMiniProgramRedPack(t *testing.T) {
+	server := newRedPackServer(t)
+	pemCert, keyCert := newTestCert(t)
+	c := newTestClient(t, server, WithTlsCert(pemCert, keyCert), WithSignType(SignTypeHMACSHA256))
+	r, err := c.SendMiniProgramRedPack(context.Background(), SendMiniProgramRedPack{RedPack: testRedPack()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// package 需urlencode
+	if !strings.HasPrefix(r.Package, "sendid%3D242e8abd") || strings.Contains(r.Package, "&") {
+		t.Fatalf("package not encoded %s", r.Package)
+	}
+	if r.SignType != SignTypeMD5 || r.Timestamp == "" || r.NonceStr == "" {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	content := fmt.Sprintf("appId=%s&nonceStr=%s&package=%s&timeStamp=%s", testAppId, r.NonceStr, r.Package, r.Timestamp)
+	if sign := (md5Signer{}).Sign(content, testMchSecret); r.PaySign != sign {
+		t.Fatalf("unexpected pay sign %s, want %s", r.PaySign, sign)
+	}
+}
+
+func TestClient_SendMiniProgramRedPackFail(t *testing.T) {
+	server := newRedPackServer(t)
+	pemCert, keyCert := newTestCert(t)
+	c := newTestClient(t, server, WithTlsCert(pemCert, keyCert))
+	p := SendMiniProgramRedPack{RedPack: testRedPack()}
+	p.TotalAmount = 100000
+	r, err := c.SendMiniProgramRedPack(context.Background(), p)
+	var pErr PayError
+	if !errors.As(err, &pErr) || pErr.ErrCode != "NOTENOUGH" {
+		t.Fatalf("expected NOTENOUGH, got %v", err)
+	}
+	if r.PaySign != "" || r.Package != "" {
+		t.Fatalf("unexpected pay params %+v", r)
+	}
+}
@@ -0,0 +1,256 @@
+package wxpay
+
+// 红包状态
+const (
+	RedPackStatusSending   = "SENDING"   // 发放中
+	RedPackStatusSent      = "SENT"      // 已发放待领取
+	RedPackStatusFailed    = "FAILED"    // 发放失败
+	RedPackStatusReceived  = "RECEIVED"  // 已领取
+	RedPackStatusRefunding = "RFUND_ING" // 退款中
+	RedPackStatusRefund    = "REFUND"    // 已退款
+)
+
+// 红包场景
+const (
+	RedPackSceneProduct1 = "PRODUCT_1" // 商品促销
+	RedPackSceneProduct2 = "PRODUCT_2" // 抽奖
+	RedPackSceneProduct3 = "PRODUCT_3" // 虚拟物品兑奖
+	RedPackSceneProduct4 = "PRODUCT_4" // 企业内部福利
+	RedPackSceneProduct5 = "PRODUCT_5" // 渠道分润
+	RedPackSceneProduct6 = "PRODUCT_6" // 保险回馈
+	RedPackSceneProduct7 = "PRODUCT_7" // 彩票派奖
+	RedPackSceneProduct8 = "PRODUCT_8" // 税务刮奖
+)
+
+const (
+	kRedPackAmtTypeAllRand = "ALL_RAND" // 裂变红包金额设置方式，全部随机
+	kRedPackBillTypeMCHT   = "MCHT"     // 红包查询订单类型，通过商户订单号获取红包信息
+)
+
+// 红包接口使用 wxappid、mch_id 字段与MD5签名，且不传签名类型
+var redPackFields = ParamFields{AppId: "wxappid", MchId: kFieldMchId}
+
+// RedPack 红包公共参数，由 SendRedPack、SendGroupRedPack 嵌入
+type RedPack struct {
+	AuxParam
+	MchBillNo   string `xml:"mch_billno" json:"mch_billno"`                   // 商户订单号（每个订单号必须唯一，只能是数字或字母，28位以内），接口根据商户订单号支持重入，如出现超时可再调用
+	SendName    string `xml:"send_name" json:"send_name"`                     // 红包发送者名称，32个字符以内
+	ReOpenId    string `xml:"re_openid" json:"re_openid"`                     // 接受红包的用户openid，裂变红包为种子用户openid
+	TotalAmount Fen    `xml:"total_amount" json:"total_amount"`               // 付款金额，单位分，裂变红包为红包总金额
+	TotalNum    int    `xml:"total_num" json:"total_num"`                     // 红包发放总人数，普通红包为1，裂变红包为3-20
+	Wishing     string `xml:"wishing" json:"wishing"`                         // 红包祝福语，128个字符以内
+	ActName     string `xml:"act_name" json:"act_name"`                       // 活动名称，32个字符以内
+	Remark      string `xml:"remark" json:"remark"`                           // 备注信息，256个字符以内
+	SceneId     string `xml:"scene_id,omitempty" json:"scene_id,omitempty"`   // 发放红包使用场景，红包金额大于200元或者小于1元时必传
+	RiskInfo    string `xml:"risk_info,omitempty" json:"risk_info,omitempty"` // 活动信息，urlencode后的 posttime、mobile、deviceid、clientversion
+}
+
+func (r RedPack) NeedVerify() bool {
+	return false
+}
+
+func (r RedPack) NeedTlsCert() bool {
+	return true
+}
+
+func (r RedPack) ReturnType() string {
+	return "xml"
+}
+
+// 红包接口只支持MD5签名，且不传签名类型
+func (r RedPack) SignType() string {
+	return SignTypeMD5
+}
+
+func (r RedPack) Fields() ParamFields {
+	return redPackFields
+}
+
+func (r RedPack) validate(v *validator) {
+	v.mchBillNo(r.MchBillNo)
+	if v.required("send_name", r.SendName) {
+		v.maxLen("send_name", r.SendName, 32)
+	}
+	v.required("re_openid", r.ReOpenId)
+	v.amount("total_amount", r.TotalAmount)
+	if v.required("wishing", r.Wishing) {
+		v.maxLen("wishing", r.Wishing, 128)
+	}
+	if v.required("act_name", r.ActName) {
+		v.maxLen("act_name", r.ActName, 32)
+	}
+	if v.required("remark", r.Remark) {
+		v.maxLen("remark", r.Remark, 256)
+	}
+}
+
+// RedPackRsp 发放红包响应参数，result_code 为 FAIL 且 err_code 为 SYSTEMERROR 时结果未知，需使用原商户订单号重试或查询
+type RedPackRsp struct {
+	PayError
+	MchBillNo   string `xml:"mch_billno" json:"mch_billno"`     // 商户订单号
+	MchId       string `xml:"mch_id" json:"mch_id"`             // 微信支付分配的商户号
+	WxAppId     string `xml:"wxappid" json:"wxappid"`           // 商户appid
+	ReOpenId    string `xml:"re_openid" json:"re_openid"`       // 接受收红包的用户openid
+	TotalAmount Fen    `xml:"total_amount" json:"total_amount"` // 付款金额，单位分
+	SendListId  string `xml:"send_listid" json:"send_listid"`   // 红包订单的微信单号
+}
+
+/* 发放普通红包 */
+
+// SendRedPack 发放普通红包 https://pay.weixin.qq.com/wiki/doc/api/tools/cash_coupon.php?chapter=13_4&index=3
+type SendRedPack struct {
+	RedPack
+	ClientIp string `xml:"client_ip" json:"client_ip"` // 调用接口的机器Ip地址
+}
+
+func (s SendRedPack) ApiPath() string {
+	return "/mmpaymkttransfers/sendredpack"
+}
+
+func (s SendRedPack) Validate() error {
+	var v validator
+	s.validate(&v)
+	if s.TotalNum != 1 {
+		v.add("total_num", "must be 1")
+	}
+	if v.required("client_ip", s.ClientIp) {
+		v.ip("client_ip", s.ClientIp)
+	}
+	return v.err()
+}
+
+/* 发放裂变红包 */
+
+// SendGroupRedPack 发放裂变红包 https://pay.weixin.qq.com/wiki/doc/api/tools/cash_coupon.php?chapter=13_5&index=4
+type SendGroupRedPack struct {
+	RedPack
+	AmtType string `xml:"amt_type" json:"amt_type"` // 红包金额设置方式，ALL_RAND—全部随机，为空时自动填充
+}
+
+func (s SendGroupRedPack) ApiPath() string {
+	return "/mmpaymkttransfers/sendgroupredpack"
+}
+
+func (s SendGroupRedPack) Validate() error {
+	var v validator
+	s.validate(&v)
+	if s.TotalNum < 3 || s.TotalNum > 20 {
+		v.add("total_num", "must be between 3 and 20")
+	}
+	if s.AmtType != kRedPackAmtTypeAllRand {
+		v.add("amt_type", "must be ALL_RAND")
+	}
+	return v.err()
+}
+
+/* 查询红包记录 */
+
+// GetHbInfo 查询红包记录 https://pay.weixin.qq.com/wiki/doc/api/tools/cash_coupon.php?chapter=13_6&index=5
+type GetHbInfo struct {
+	AuxParam
+	MchBillNo string `xml:"mch_billno" json:"mch_billno"` // 商户发放红包的商户订单号
+	BillType  string `xml:"bill_type" json:"bill_type"`   // MCHT:通过商户订单号获取红包信息，为空时自动填充
+}
+
+func (g GetHbInfo) NeedVerify() bool {
+	return false
+}
+
+func (g GetHbInfo) NeedTlsCert() bool {
+	return true
+}
+
+func (g GetHbInfo) ReturnType() string {
+	return "xml"
+}
+
+func (g GetHbInfo) ApiPath() string {
+	return "/mmpaymkttransfers/gethbinfo"
+}
+
+// 查询红包记录只支持MD5签名，且不传签名类型
+func (g GetHbInfo) SignType() string {
+	return SignTypeMD5
+}
+
+func (g GetHbInfo) Fields() ParamFields {
+	return ParamFields{AppId: kFieldAppId, MchId: kFieldMchId}
+}
+
+func (g GetHbInfo) Validate() error {
+	var v validator
+	v.mchBillNo(g.MchBillNo)
+	if g.BillType != kRedPackBillTypeMCHT {
+		v.add("bill_type", "must be MCHT")
+	}
+	return v.err()
+}
+
+// GetHbInfoRsp 查询红包记录响应参数
+type GetHbInfoRsp struct {
+	PayError
+	MchBillNo    string       `xml:"mch_billno" json:"mch_billno"`       // 商户使用查询API填写的商户单号的原路返回
+	MchId        string       `xml:"mch_id" json:"mch_id"`               // 微信支付分配的商户号
+	DetailId     string       `xml:"detail_id" json:"detail_id"`         // 使用API发放现金红包时返回的红包单号
+	Status       string       `xml:"status" json:"status"`               // 红包状态，SENDING、SENT、FAILED、RECEIVED、RFUND_ING、REFUND
+	SendType     string       `xml:"send_type" json:"send_type"`         // 发放类型，API:通过API接口发放 UPLOAD:通过上传文件方式发放 ACTIVITY:通过活动方式发放
+	HbType       string       `xml:"hb_type" json:"hb_type"`             // 红包类型，GROUP:裂变红包 NORMAL:普通红包
+	TotalNum     int          `xml:"total_num" json:"total_num"`         // 红包个数
+	TotalAmount  Fen          `xml:"total_amount" json:"total_amount"`   // 红包总金额，单位分
+	Reason       string       `xml:"reason" json:"reason"`               // 发送失败原因
+	SendTime     string       `xml:"send_time" json:"send_time"`         // 红包发送时间
+	RefundTime   string       `xml:"refund_time" json:"refund_time"`     // 红包的退款时间（如果其未领取的退款）
+	RefundAmount Fen          `xml:"refund_amount" json:"refund_amount"` // 红包退款金额
+	Wishing      string       `xml:"wishing" json:"wishing"`             // 祝福语
+	Remark       string       `xml:"remark" json:"remark"`               // 活动描述，低版本微信可见
+	ActName      string       `xml:"act_name" json:"act_name"`           // 发红包的活动名称
+	HbList       []HbInfoItem `xml:"hblist>hbinfo" json:"hblist"`        // 裂变红包的领取列表
+}
+
+// HbInfoItem 红包领取记录
+type HbInfoItem struct {
+	OpenId  string `xml:"openid" json:"openid"`     // 领取红包的openid
+	Amount  Fen    `xml:"amount" json:"amount"`     // 领取金额，单位分
+	RcvTime string `xml:"rcv_time" json:"rcv_time"` // 领取红包的时间
+}
+
+/* 发放小程序红包 */
+
+const kRedPackNotifyWayMiniProgram = "MINI_PROGRAM_JSAPI" // 小程序红包通知用户方式
+
+// SendMiniProgramRedPack 发放小程序红包 https://pay.weixin.qq.com/wiki/doc/api/tools/cash_coupon.php?chapter=18_2&index=3
+type SendMiniProgramRedPack struct {
+	RedPack
+	NotifyWay string `xml:"notify_way" json:"notify_way"` // 通知用户形式，MINI_PROGRAM_JSAPI，为空时自动填充
+}
+
+func (s SendMiniProgramRedPack) ApiPath() string {
+	return "/mmpaymkttransfers/sendminiprogramhb"
+}
+
+func (s SendMiniProgramRedPack) Validate() error {
+	var v validator
+	s.validate(&v)
+	if s.TotalNum != 1 {
+		v.add("total_num", "must be 1")
+	}
+	if s.NotifyWay != kRedPackNotifyWayMiniProgram {
+		v.add("notify_way", "must be MINI_PROGRAM_JSAPI")
+	}
+	return v.err()
+}
+
+// SendMiniProgramRedPackRsp 发放小程序红包响应参数
+type SendMiniProgramRedPackRsp struct {
+	RedPackRsp
+	Package string `xml:"package" json:"package"` // 返回jaspi的入参package的值
+}
+
+// MiniProgramRedPackRsp 小程序拉起红包 wx.sendBizRedPacket 的参数
+type MiniProgramRedPackRsp struct {
+	Timestamp string `json:"timeStamp"`
+	NonceStr  string `json:"nonceStr"`
+	Package   string `json:"package"`
+	SignType  string `json:"signType"`
+	PaySign   string `json:"paySign"`
+}
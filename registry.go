@@ -0,0 +1,244 @@
+package wxpay
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	ErrWxMerchantNotFound      = errors.New("wxpay: merchant credential not found")
+	ErrWxNotifyMchIdNotFound   = errors.New("wxpay: mch_id not found in notification")
+	ErrWxMerchantMchIdMismatch = errors.New("wxpay: merchant credential mch_id mismatch")
+)
+
+// 创建商户客户端的超时时间，包括从凭据来源获取凭据
+const kRegistryCreateTimeout = 10 * time.Second
+
+// MerchantCredential 商户凭据，由 CredentialSource 按商户号提供
+type MerchantCredential struct {
+	AppId     string       // 商户号绑定的appid
+	Secret    string       // appid对应的AppSecret
+	MchId     string       // 商户号，为空时使用查询的商户号
+	MchSecret string       // 商户支付密钥
+	PemCert   []byte       // 商户证书内容，企业付款、退款等接口需要
+	KeyCert   []byte       // 商户证书私钥内容
+	Options   []OptionFunc // 该商户额外的客户端配置，如签名类型，在 Registry 公共配置之后应用
+}
+
+// CredentialSource 商户凭据来源，如数据库、配置中心，商户不存在时返回 ErrWxMerchantNotFound
+type CredentialSource interface {
+	Credential(ctx context.Context, mchId string) (*MerchantCredential, error)
+}
+
+// CredentialSourceFunc 函数形式的商户凭据来源
+type CredentialSourceFunc func(ctx context.Context, mchId string) (*MerchantCredential, error)
+
+func (f CredentialSourceFunc) Credential(ctx context.Context, mchId string) (*MerchantCredential, error) {
+	return f(ctx, mchId)
+}
+
+// Registry 多商户客户端管理，按商户号懒加载并缓存客户端，可在多个 goroutine 中并发使用
+// 所有客户端共享公共配置中的 http.Client（默认 http.DefaultClient）及其连接池，
+// 每个商户的证书只在首次创建客户端时解析一次，同一appid的商户共享接口调用凭据。
+// 服务商模式下以服务商商户号管理客户端，通知同样按服务商商户号（mch_id）查找，子商户号见通知中的 SubMchId
+type Registry struct {
+	source  CredentialSource
+	opts    []OptionFunc
+	mu      sync.Mutex
+	clients map[string]*Client
+	calls   map[string]*registryCall
+	tokens  map[string]AccessTokenProvider
+}
+
+// 正在进行中的客户端创建，同一商户的并发调用共享同一次创建结果
+type registryCall struct {
+	done   chan struct{}
+	client *Client
+	err    error
+}
+
+// NewRegistry 创建多商户客户端管理，opts 为所有商户客户端的公共配置
+func NewRegistry(source CredentialSource, opts ...OptionFunc) *Registry {
+	return &Registry{
+		source:  source,
+		opts:    opts,
+		clients: make(map[string]*Client),
+		calls:   make(map[string]*registryCall),
+		tokens:  make(map[string]AccessTokenProvider),
+	}
+}
+
+// Client 获取商户号对应的客户端，首次获取时从凭据来源创建，创建失败不会缓存
+func (r *Registry) Client(ctx context.Context, mchId string) (*Client, error) {
+	r.mu.Lock()
+	if c, ok := r.clients[mchId]; ok {
+		r.mu.Unlock()
+		return c, nil
+	}
+	// 没有进行中的创建请求时发起创建，所有调用方各自等待创建结果
+	call, ok := r.calls[mchId]
+	if !ok {
+		call = &registryCall{done: make(chan struct{})}
+		r.calls[mchId] = call
+		go r.create(context.WithoutCancel(ctx), mchId, call)
+	}
+	r.mu.Unlock()
+	select {
+	case <-call.done:
+		return call.client, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// 创建客户端并缓存，ctx 不随发起创建的调用方取消，如通知请求的连接断开
+func (r *Registry) create(ctx context.Context, mchId string, call *registryCall) {
+	ctx, cancel := context.WithTimeout(ctx, kRegistryCreateTimeout)
+	defer cancel()
+	call.client, call.err = r.newClient(ctx, mchId)
+	r.mu.Lock()
+	// 创建期间调用了 Invalidate 时不缓存，结果可能来自更新前的凭据
+	if r.calls[mchId] == call {
+		if call.err == nil {
+			r.clients[mchId] = call.client
+		}
+		delete(r.calls, mchId)
+	}
+	r.mu.Unlock()
+	close(call.done)
+}
+
+// Invalidate 移除商户号对应的客户端缓存，商户凭据或证书更新后调用，下次获取时重新创建，
+// 调用时正在进行的创建结果只返回给已在等待的调用方，不会被缓存
+func (r *Registry) Invalidate(mchId string) {
+	r.mu.Lock()
+	delete(r.clients, mchId)
+	delete(r.calls, mchId)
+	r.mu.Unlock()
+}
+
+// 从凭据来源创建客户端
+func (r *Registry) newClient(ctx context.Context, mchId string) (*Client, error) {
+	if mchId == "" {
+		return nil, ErrWxMerchantNotFound
+	}
+	cred, err := r.source.Credential(ctx, mchId)
+	if err != nil {
+		return nil, err
+	}
+	if cred == nil {
+		return nil, ErrWxMerchantNotFound
+	}
+	if cred.MchId != "" && cred.MchId != mchId {
+		return nil, fmt.Errorf("%w, want %s, got %s", ErrWxMerchantMchIdMismatch, mchId, cred.MchId)
+	}
+	shared, err := r.sharedTokenProvider(cred)
+	if err != nil {
+		return nil, err
+	}
+	opts := make([]OptionFunc, 0, len(r.opts)+len(cred.Options)+3)
+	opts = append(opts, withSharedAccessTokenProvider(shared))
+	opts = append(opts, r.opts...)
+	opts = append(opts, WithMchInformation(mchId, cred.MchSecret))
+	if len(cred.PemCert) > 0 || len(cred.KeyCert) > 0 {
+		opts = append(opts, WithTlsCert(cred.PemCert, cred.KeyCert))
+	}
+	opts = append(opts, cred.Options...)
+	return New(cred.AppId, cred.Secret, opts...)
+}
+
+// 同一appid的商户共享内存凭据管理，避免各自刷新导致凭据互相失效，
+// 凭据通过公共配置创建的客户端获取，配置了凭据管理或凭据存储的客户端不使用
+func (r *Registry) sharedTokenProvider(cred *MerchantCredential) (AccessTokenProvider, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if provider, ok := r.tokens[cred.AppId]; ok {
+		return provider, nil
+	}
+	c, err := New(cred.AppId, cred.Secret, r.opts...)
+	if err != nil {
+		return nil, err
+	}
+	provider := NewAccessTokenProvider(c)
+	r.tokens[cred.AppId] = provider
+	return provider, nil
+}
+
+// 设置共享的凭据管理，未设置 WithAccessTokenProvider 与 WithTokenStore 时生效
+func withSharedAccessTokenProvider(provider AccessTokenProvider) OptionFunc {
+	return func(c *Client) {
+		c.sharedToken = provider
+	}
+}
+
+// ParsePayNotify 解析支付结果通知，按通知内容中的 mch_id 找到对应商户的客户端并验证签名，服务商模式下为服务商商户号
+func (r *Registry) ParsePayNotify(req *http.Request) (client *Client, result *PayNotification, err error) {
+	client, data, err := r.notifyClient(req)
+	if err != nil {
+		return
+	}
+	result, err = client.parsePayNotify(data)
+	return
+}
+
+// PayNotifyHandler 多商户支付结果通知处理器，验证通过后调用 fn，client 为通知所属商户的客户端
+func (r *Registry) PayNotifyHandler(fn func(ctx context.Context, client *Client, notification *PayNotification) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		client, notification, err := r.ParsePayNotify(req)
+		if err == nil {
+			err = fn(req.Context(), client, notification)
+		}
+		WriteNotifyAck(w, err)
+	})
+}
+
+// ParseRefundNotify 解析退款结果通知，按通知内容中的 mch_id 找到对应商户的客户端并解密
+func (r *Registry) ParseRefundNotify(req *http.Request) (client *Client, result *RefundNotification, err error) {
+	client, data, err := r.notifyClient(req)
+	if err != nil {
+		return
+	}
+	result, err = client.parseRefundNotify(data)
+	return
+}
+
+// RefundNotifyHandler 多商户退款结果通知处理器，解密成功后调用 fn，client 为通知所属商户的客户端
+func (r *Registry) RefundNotifyHandler(fn func(ctx context.Context, client *Client, notification *RefundNotification) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		client, notification, err := r.ParseRefundNotify(req)
+		if err == nil {
+			err = fn(req.Context(), client, notification)
+		}
+		WriteNotifyAck(w, err)
+	})
+}
+
+// 读取通知内容并按 mch_id 获取商户客户端，服务商模式下通知使用服务商的密钥签名，不按 sub_mch_id 查找
+func (r *Registry) notifyClient(req *http.Request) (client *Client, data []byte, err error) {
+	if data, err = readNotifyBody(req); err != nil {
+		return
+	}
+	resultMap := make(map[string]string)
+	if err = xml.Unmarshal(data, (*payXml)(&resultMap)); err != nil {
+		return
+	}
+	mchId := resultMap[kFieldMchId]
+	if mchId == "" {
+		if ReturnCode(resultMap[kFieldReturnCode]).IsFailure() {
+			return nil, nil, PayError{ReturnCode: ReturnCode(resultMap[kFieldReturnCode]), ReturnMsg: resultMap["return_msg"]}
+		}
+		return nil, nil, ErrWxNotifyMchIdNotFound
+	}
+	if client, err = r.Client(req.Context(), mchId); err != nil {
+		return
+	}
+	if client.onReceivedData != nil {
+		client.onReceivedData(req.Method, data)
+	}
+	return
+}
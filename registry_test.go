@@ -0,0 +1,221 @@
+package wxpay
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	testRegistryMchId     = "1900000110"
+	testRegistryMchSecret = "192006250b4c09247ec02edce69f6a2e"
+)
+
+// 两个商户使用同一appid，第二个商户带证书
+func newTestRegistry(t *testing.T, opts ...OptionFunc) (*Registry, *int32) {
+	t.Helper()
+	pemCert, keyCert := newTestCert(t)
+	var calls int32
+	source := CredentialSourceFunc(func(ctx context.Context, mchId string) (*MerchantCredential, error) {
+		atomic.AddInt32(&calls, 1)
+		switch mchId {
+		case testMchId:
+			return &MerchantCredential{AppId: testAppId, Secret: testSecret, MchSecret: testMchSecret}, nil
+		case testRegistryMchId:
+			return &MerchantCredential{AppId: testAppId, Secret: testSecret, MchId: mchId, MchSecret: testRegistryMchSecret, PemCert: pemCert, KeyCert: keyCert}, nil
+		}
+		return nil, ErrWxMerchantNotFound
+	})
+	return NewRegistry(source, opts...), &calls
+}
+
+// 发起创建的调用方取消后，等待同一商户的其他调用方仍能拿到客户端
+func TestRegistry_ClientLeaderCancel(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	r := NewRegistry(CredentialSourceFunc(func(ctx context.Context, mchId string) (*MerchantCredential, error) {
+		close(started)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return &MerchantCredential{AppId: testAppId, Secret: testSecret, MchSecret: testMchSecret}, nil
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := r.Client(ctx, testMchId)
+		leaderErr <- err
+	}()
+	<-started
+	waiter := make(chan *Client, 1)
+	go func() {
+		c, err := r.Client(context.Background(), testMchId)
+		if err != nil {
+			t.Error(err)
+		}
+		waiter <- c
+	}()
+	cancel()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	close(release)
+	if c := <-waiter; c == nil || c.mchId != testMchId {
+		t.Fatalf("unexpected client %+v", c)
+	}
+}
+
+// 创建期间调用 Invalidate 时，创建结果不缓存，之后的获取重新创建
+func TestRegistry_InvalidateDuringCreate(t *testing.T) {
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	var calls int32
+	r := NewRegistry(CredentialSourceFunc(func(ctx context.Context, mchId string) (*MerchantCredential, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			started <- struct{}{}
+			<-release
+		}
+		return &MerchantCredential{AppId: testAppId, Secret: testSecret, MchSecret: testMchSecret}, nil
+	}))
+	first := make(chan *Client, 1)
+	go func() {
+		c, _ := r.Client(context.Background(), testMchId)
+		first <- c
+	}()
+	<-started
+	r.Invalidate(testMchId)
+	close(release)
+	stale := <-first
+	c, err := r.Client(context.Background(), testMchId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == stale || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("expected a new client after invalidate, got %d credential calls", calls)
+	}
+}
+
+// 商户配置了凭据存储时不使用共享的凭据管理
+func TestRegistry_ClientTokenStore(t *testing.T) {
+	store := NewMemoryTokenStore()
+	r := NewRegistry(CredentialSourceFunc(func(ctx context.Context, mchId string) (*MerchantCredential, error) {
+		return &MerchantCredential{AppId: testAppId, Secret: testSecret, MchSecret: testMchSecret, Options: []OptionFunc{WithTokenStore(store)}}, nil
+	}))
+	c, err := r.Client(context.Background(), testMchId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.tokenProvider.(*storeAccessTokenProvider); !ok {
+		t.Fatalf("expected store access token provider, got %T", c.tokenProvider)
+	}
+}
+
+func TestRegistry_Client(t *testing.T) {
+	httpClient := &http.Client{}
+	r, calls := newTestRegistry(t, WithHttpClient(httpClient))
+
+	// 并发获取同一商户只创建一次
+	var wg sync.WaitGroup
+	clients := make([]*Client, 10)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := r.Client(context.Background(), testRegistryMchId)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			clients[i] = c
+		}(i)
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Fatalf("expected 1 credential call, got %d", n)
+	}
+	for _, c := range clients {
+		if c != clients[0] {
+			t.Fatal("expected the same client")
+		}
+	}
+	if clients[0].mchId != testRegistryMchId || clients[0].tlsClient == nil {
+		t.Fatalf("unexpected client mch_id %s", clients[0].mchId)
+	}
+
+	other, err := r.Client(context.Background(), testMchId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 共享 http.Client 与同一appid的凭据管理
+	if other.client != httpClient || clients[0].client != httpClient {
+		t.Fatal("expected shared http client")
+	}
+	if other.tokenProvider != clients[0].tokenProvider {
+		t.Fatal("expected shared access token provider")
+	}
+
+	// 商户不存在时不缓存错误
+	for i := 0; i < 2; i++ {
+		if _, err = r.Client(context.Background(), "unknown"); !errors.Is(err, ErrWxMerchantNotFound) {
+			t.Fatalf("expected ErrWxMerchantNotFound, got %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(calls); n != 4 {
+		t.Fatalf("expected 4 credential calls, got %d", n)
+	}
+
+	r.Invalidate(testMchId)
+	if c, _ := r.Client(context.Background(), testMchId); c == other {
+		t.Fatal("expected a new client after invalidate")
+	}
+}
+
+func TestRegistry_PayNotifyHandler(t *testing.T) {
+	r, _ := newTestRegistry(t)
+	signer, err := New(testAppId, testSecret, WithMchInformation(testRegistryMchId, testRegistryMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newTestPayNotify()
+	m[kFieldMchId] = testRegistryMchId
+	body := newTestNotifyBody(t, signer, m)
+
+	var got *Client
+	handler := r.PayNotifyHandler(func(ctx context.Context, client *Client, notification *PayNotification) error {
+		got = client
+		return nil
+	})
+	serve := func(body []byte) NotifyAck {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body)))
+		var ack NotifyAck
+		if err := xml.Unmarshal(rec.Body.Bytes(), &ack); err != nil {
+			t.Fatal(err)
+		}
+		return ack
+	}
+	if ack := serve(body); ack.ReturnCode != ReturnCodeSuccess {
+		t.Fatalf("ack = %+v, want SUCCESS", ack)
+	}
+	if got == nil || got.mchId != testRegistryMchId {
+		t.Fatal("notification routed to the wrong merchant")
+	}
+
+	// 使用其他商户密钥签名的通知验证失败
+	m = newTestPayNotify()
+	m[kFieldMchId] = testRegistryMchId
+	other, _ := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if ack := serve(newTestNotifyBody(t, other, m)); ack.ReturnCode != ReturnCodeFail {
+		t.Fatalf("ack = %+v, want FAIL", ack)
+	}
+	delete(m, kFieldMchId)
+	delete(m, kFieldSign)
+	if ack := serve(newTestNotifyBody(t, other, m)); ack.ReturnCode != ReturnCodeFail {
+		t.Fatalf("ack = %+v, want FAIL", ack)
+	}
+}
@@ -0,0 +1,88 @@
+package wxpay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// RetryPolicy 重试策略，只对 Param.Idempotent 返回 true 的接口生效（查询订单、关闭订单、申请退款、查询退款、撤销订单），
+// 网络错误、非2xx状态码与 ErrCodes 中的业务错误码会重试，重试时使用相同的请求内容，不会生成新的 nonce_str
+type RetryPolicy struct {
+	MaxAttempts int           // 最大请求次数（含首次请求），小于等于1时不重试
+	BaseDelay   time.Duration // 首次重试前的等待时间，之后每次翻倍
+	MaxDelay    time.Duration // 单次等待时间上限，为0时不限制
+	ErrCodes    []string      // 可重试的 err_code，为空时使用 SYSTEMERROR、BIZERR_NEED_RETRY
+}
+
+// DefaultRetryPolicy 默认重试策略，最多请求3次，等待时间从200毫秒开始翻倍，最长2秒
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+		ErrCodes:    []string{PayErrCodeSystemError, PayErrCodeBizNeedRetry},
+	}
+}
+
+// 设置重试策略，默认不重试
+func WithRetryPolicy(policy RetryPolicy) OptionFunc {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// 第 attempt 次请求失败后是否需要重试，errCode 为业务结果失败时返回的 err_code
+func (p RetryPolicy) shouldRetry(ctx context.Context, attempt int, err error, errCode string) bool {
+	if attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		// 网络错误、读取响应失败与网关错误
+		var nErr net.Error
+		if errors.As(err, &nErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, ErrWxHttpStatus) {
+			return true
+		}
+		var pErr PayError
+		if !errors.As(err, &pErr) {
+			return false
+		}
+		errCode = pErr.ErrCode
+	}
+	if errCode == "" {
+		return false
+	}
+	errCodes := p.ErrCodes
+	if len(errCodes) == 0 {
+		errCodes = []string{PayErrCodeSystemError, PayErrCodeBizNeedRetry}
+	}
+	for _, code := range errCodes {
+		if errCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// 第 attempt 次请求失败后的等待时间，指数退避并加入随机抖动，避免大量请求同时重试
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// 等待时间在 [delay/2, delay] 之间
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
@@ -0,0 +1,217 @@
+package wxpay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// 模拟前 failures 次返回 errCode 的支付接口，记录每次请求的 nonce_str 与签名
+func newRetryServer(t *testing.T, failures int32, errCode string) (server *httptest.Server, requests *[]map[string]string) {
+	t.Helper()
+	signer, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	requests = new([]map[string]string)
+	var count int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			writeTestPayXml(w, signer, map[string]string{"return_code": "FAIL", "return_msg": err.Error()})
+			return
+		}
+		mu.Lock()
+		*requests = append(*requests, m)
+		mu.Unlock()
+		if atomic.AddInt32(&count, 1) <= failures {
+			writeTestPayXml(w, signer, map[string]string{"return_code": "SUCCESS", "result_code": "FAIL", "err_code": errCode, "err_code_des": "系统超时"})
+			return
+		}
+		writeTestPayXml(w, signer, map[string]string{
+			"return_code":  "SUCCESS",
+			"result_code":  "SUCCESS",
+			"appid":        testAppId,
+			"mch_id":       testMchId,
+			"nonce_str":    signer.createNonceStr(),
+			"out_trade_no": m["out_trade_no"],
+			"trade_state":  TradeStateSuccess,
+			"total_fee":    "1",
+			"prepay_id":    "wx" + m["out_trade_no"],
+		})
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pay/orderquery", handler)
+	mux.HandleFunc("/pay/closeorder", handler)
+	mux.HandleFunc("/pay/unifiedorder", handler)
+	mux.HandleFunc("/secapi/pay/reverse", handler)
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return
+}
+
+func testRetryPolicy() RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.BaseDelay = time.Millisecond
+	p.MaxDelay = 5 * time.Millisecond
+	return p
+}
+
+func TestClient_RetrySameRequest(t *testing.T) {
+	server, requests := newRetryServer(t, 2, PayErrCodeSystemError)
+	c := newTestClient(t, server, WithRetryPolicy(testRetryPolicy()))
+	r, err := c.TradeOrderQuery(context.Background(), TradeOrderQuery{OutTradeNo: "TEST2023112717521212345678"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TradeState != TradeStateSuccess {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	if len(*requests) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(*requests))
+	}
+	// 重试时使用相同的 nonce_str 与签名
+	for _, m := range (*requests)[1:] {
+		if m[kFieldNonceStr] != (*requests)[0][kFieldNonceStr] || m[kFieldSign] != (*requests)[0][kFieldSign] {
+			t.Fatal("expected the same signed request on retry")
+		}
+	}
+}
+
+func TestClient_RetryExhausted(t *testing.T) {
+	server, requests := newRetryServer(t, 5, PayErrCodeSystemError)
+	c := newTestClient(t, server, WithRetryPolicy(testRetryPolicy()))
+	// 重试次数用完后返回最后一次的结果
+	r, err := c.TradeCloseOrder(context.Background(), TradeCloseOrder{OutTradeNo: "TEST2023112717521212345678"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ErrCode != PayErrCodeSystemError {
+		t.Fatalf("expected SYSTEMERROR, got %+v", r)
+	}
+	if len(*requests) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(*requests))
+	}
+}
+
+// 撤销订单返回系统错误时按重试策略重试
+func TestClient_RetryReverse(t *testing.T) {
+	server, requests := newRetryServer(t, 2, PayErrCodeSystemError)
+	pemCert, keyCert := newTestCert(t)
+	c := newTestClient(t, server, WithRetryPolicy(testRetryPolicy()), WithTlsCert(pemCert, keyCert))
+	r, err := c.TradeReverse(context.Background(), TradeReverse{OutTradeNo: "TEST2023112717521212345678"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ResultCode != string(ReturnCodeSuccess) || len(*requests) != 3 {
+		t.Fatalf("unexpected result %+v after %d attempts", r, len(*requests))
+	}
+}
+
+func TestClient_RetryNotIdempotent(t *testing.T) {
+	server, requests := newRetryServer(t, 1, PayErrCodeSystemError)
+	c := newTestClient(t, server, WithRetryPolicy(testRetryPolicy()))
+	var p TradeApp
+	p.Body = "支付测试"
+	p.OutTradeNo = "TEST2023112717521212345678"
+	p.TotalFee = 1
+	p.SpbillCreateIp = "127.0.0.1"
+	p.NotifyUrl = "https://www.weixin.qq.com/wxpay/pay.php"
+	// 统一下单不自动重试
+	if _, err := c.TradeApp(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("expected 1 attempt, got %d", len(*requests))
+	}
+}
+
+func TestClient_RetryErrCode(t *testing.T) {
+	server, requests := newRetryServer(t, 1, "ORDERNOTEXIST")
+	c := newTestClient(t, server, WithRetryPolicy(testRetryPolicy()))
+	if _, err := c.TradeOrderQuery(context.Background(), TradeOrderQuery{OutTradeNo: "TEST2023112717521212345678"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("expected 1 attempt, got %d", len(*requests))
+	}
+}
+
+// 前 failures 次请求失败，fail 返回失败时的响应，为空时返回网络错误
+type failingTransport struct {
+	failures int32
+	count    int32
+	fail     func() *http.Response
+}
+
+func (f *failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if atomic.AddInt32(&f.count, 1) <= f.failures {
+		if f.fail != nil {
+			return f.fail(), nil
+		}
+		return nil, errors.New("connection reset by peer")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClient_RetryNetworkError(t *testing.T) {
+	server, requests := newRetryServer(t, 0, "")
+	transport := &failingTransport{failures: 2}
+	c := newTestClient(t, server, WithRetryPolicy(testRetryPolicy()), WithHttpClient(&http.Client{Transport: transport}))
+	if _, err := c.TradeOrderQuery(context.Background(), TradeOrderQuery{OutTradeNo: "TEST2023112717521212345678"}); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&transport.count) != 3 || len(*requests) != 1 {
+		t.Fatalf("expected 3 attempts, got %d", transport.count)
+	}
+}
+
+// 网关返回非2xx的html页面与读取响应失败时重试
+func TestClient_RetryTransportFailure(t *testing.T) {
+	failures := map[string]func() *http.Response{
+		"gateway": func() *http.Response {
+			return &http.Response{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway", Body: io.NopCloser(strings.NewReader("<html>502 Bad Gateway</html>"))}
+		},
+		"read": func() *http.Response {
+			return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Body: io.NopCloser(io.MultiReader(strings.NewReader("<xml>"), iotest.ErrReader(io.ErrUnexpectedEOF)))}
+		},
+	}
+	for name, fail := range failures {
+		server, requests := newRetryServer(t, 0, "")
+		transport := &failingTransport{failures: 2, fail: fail}
+		c := newTestClient(t, server, WithRetryPolicy(testRetryPolicy()), WithHttpClient(&http.Client{Transport: transport}))
+		if _, err := c.TradeOrderQuery(context.Background(), TradeOrderQuery{OutTradeNo: "TEST2023112717521212345678"}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if atomic.LoadInt32(&transport.count) != 3 || len(*requests) != 1 {
+			t.Fatalf("%s: expected 3 attempts, got %d", name, transport.count)
+		}
+	}
+	// 不重试时返回状态码错误
+	server, _ := newRetryServer(t, 0, "")
+	c := newTestClient(t, server, WithHttpClient(&http.Client{Transport: &failingTransport{failures: 1, fail: failures["gateway"]}}))
+	if _, err := c.TradeOrderQuery(context.Background(), TradeOrderQuery{OutTradeNo: "TEST2023112717521212345678"}); !errors.Is(err, ErrWxHttpStatus) {
+		t.Fatalf("expected ErrWxHttpStatus, got %v", err)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	for attempt, max := range []time.Duration{100, 200, 300, 300} {
+		max *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			if d := p.backoff(attempt + 1); d < max/2 || d > max {
+				t.Fatalf("attempt %d backoff %s out of [%s, %s]", attempt+1, d, max/2, max)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package wxpay
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// AccessTokenProvider 接口调用凭据管理，需要 access_token 的接口会自动通过它获取凭据
+type AccessTokenProvider interface {
+	// AccessToken 获取有效的接口调用凭据
+	AccessToken(ctx context.Context) (string, error)
+
+	// Invalidate 接口返回凭据失效（40001/42001）时调用，token 为失效的凭据，已刷新的新凭据不受影响
+	Invalidate(ctx context.Context, token string) error
+}
+
+const (
+	kAccessTokenExpiryDelta    = 5 * time.Minute  // 凭据提前过期时间，避免临近过期时使用旧凭据
+	kAccessTokenRefreshTimeout = 10 * time.Second // 刷新凭据的超时时间，刷新不受发起调用方取消的影响
+)
+
+// 内存凭据管理
+type memoryAccessTokenProvider struct {
+	client    *Client
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+	refresh   *tokenCall
+}
+
+// 正在进行中的刷新请求，多个调用方共享同一次刷新结果
+type tokenCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// NewAccessTokenProvider 创建内存凭据管理，凭据缓存至过期前5分钟，并发刷新时只请求一次微信接口
+func NewAccessTokenProvider(client *Client) AccessTokenProvider {
+	return &memoryAccessTokenProvider{client: client}
+}
+
+func (p *memoryAccessTokenProvider) AccessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	if p.token != "" && time.Now().Before(p.expiresAt) {
+		token := p.token
+		p.mu.Unlock()
+		return token, nil
+	}
+	// 没有进行中的刷新请求时发起刷新，所有调用方各自等待刷新结果
+	call := p.refresh
+	if call == nil {
+		call = &tokenCall{done: make(chan struct{})}
+		p.refresh = call
+		go p.doRefresh(context.WithoutCancel(ctx), call)
+	}
+	p.mu.Unlock()
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// 请求微信接口刷新凭据，ctx 不随发起刷新的调用方取消
+func (p *memoryAccessTokenProvider) doRefresh(ctx context.Context, call *tokenCall) {
+	ctx, cancel := context.WithTimeout(ctx, kAccessTokenRefreshTimeout)
+	defer cancel()
+	var ttl time.Duration
+	rsp, err := p.client.GetAccessToken(ctx, GetAccessToken{})
+	if err == nil {
+		call.token = rsp.AccessToken
+		ttl = accessTokenTTL(rsp.ExpiresIn)
+	}
+	call.err = err
+	p.mu.Lock()
+	if err == nil {
+		p.token = call.token
+		p.expiresAt = time.Now().Add(ttl)
+	}
+	p.refresh = nil
+	p.mu.Unlock()
+	close(call.done)
+}
+
+func (p *memoryAccessTokenProvider) Invalidate(ctx context.Context, token string) error {
+	p.mu.Lock()
+	if p.token == token {
+		p.token = ""
+	}
+	p.mu.Unlock()
+	return nil
+}
+
+// 凭据缓存时间，提前5分钟过期，有效期不足10分钟时缓存有效期的一半
+func accessTokenTTL(expiresIn int) time.Duration {
+	d := time.Duration(expiresIn) * time.Second
+	if d-kAccessTokenExpiryDelta < d/2 {
+		return d / 2
+	}
+	return d - kAccessTokenExpiryDelta
+}
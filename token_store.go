@@ -0,0 +1,350 @@
+package wxpay
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TokenStore 凭据存储，多个服务实例共享同一存储（如Redis）时，只有一个实例会请求微信接口刷新凭据，避免互相覆盖导致凭据失效
+type TokenStore interface {
+	// Get 获取凭据，不存在或已过期时返回空字符串
+	Get(ctx context.Context, key string) (string, error)
+
+	// Set 保存凭据，ttl 为有效期
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+
+	// Delete 删除凭据
+	Delete(ctx context.Context, key string) error
+
+	// Lock 获取分布式锁，阻塞至获取成功或 ctx 结束，ttl 为锁的最长持有时间，防止持有者异常退出后无法释放
+	Lock(ctx context.Context, key string, ttl time.Duration) (unlock func() error, err error)
+}
+
+const (
+	kTokenStoreKeyPrefix = "wxpay:access_token:" // 凭据存储key前缀，后接appid
+	kTokenStoreLockTTL   = 30 * time.Second      // 刷新凭据时的锁有效期
+	kTokenStorePollDelay = 20 * time.Millisecond // 等待锁释放的轮询间隔
+)
+
+// 共享存储凭据管理
+type storeAccessTokenProvider struct {
+	client *Client
+	store  TokenStore
+	key    string
+}
+
+// WithTokenStore 设置凭据存储，客户端使用 NewStoreAccessTokenProvider 管理凭据，同时设置 WithAccessTokenProvider 时以后者为准
+func WithTokenStore(store TokenStore) OptionFunc {
+	return func(c *Client) {
+		if store != nil {
+			c.tokenStore = store
+		}
+	}
+}
+
+// NewStoreAccessTokenProvider 创建共享存储凭据管理，凭据保存在 store 中，刷新时加锁，同一时间只有一个实例请求微信接口，
+// client 用于请求获取凭据接口，一般通过 WithTokenStore 设置即可
+func NewStoreAccessTokenProvider(client *Client, store TokenStore) AccessTokenProvider {
+	return &storeAccessTokenProvider{client: client, store: store, key: kTokenStoreKeyPrefix + client.appId}
+}
+
+func (p *storeAccessTokenProvider) AccessToken(ctx context.Context) (string, error) {
+	if token, err := p.store.Get(ctx, p.key); err != nil || token != "" {
+		return token, err
+	}
+	unlock, err := p.store.Lock(ctx, p.key+":lock", kTokenStoreLockTTL)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	// 获取锁后再次检查，其他实例可能已经刷新了凭据
+	if token, err := p.store.Get(ctx, p.key); err != nil || token != "" {
+		return token, err
+	}
+	rsp, err := p.client.GetAccessToken(ctx, GetAccessToken{})
+	if err != nil {
+		return "", err
+	}
+	if err = p.store.Set(ctx, p.key, rsp.AccessToken, accessTokenTTL(rsp.ExpiresIn)); err != nil {
+		return "", err
+	}
+	return rsp.AccessToken, nil
+}
+
+func (p *storeAccessTokenProvider) Invalidate(ctx context.Context, token string) error {
+	unlock, err := p.store.Lock(ctx, p.key+":lock", kTokenStoreLockTTL)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	current, err := p.store.Get(ctx, p.key)
+	if err != nil || current != token {
+		return err
+	}
+	return p.store.Delete(ctx, p.key)
+}
+
+// 轮询获取锁，直到成功或 ctx 结束
+func pollLock(ctx context.Context, tryLock func() (bool, error)) error {
+	for {
+		ok, err := tryLock()
+		if err != nil || ok {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(kTokenStorePollDelay):
+		}
+	}
+}
+
+/* 内存存储 */
+
+// MemoryTokenStore 内存凭据存储，可在同一进程的多个客户端间共享
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	values map[string]memoryTokenEntry
+	locks  map[string]time.Time
+}
+
+type memoryTokenEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// NewMemoryTokenStore 创建内存凭据存储
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{values: make(map[string]memoryTokenEntry), locks: make(map[string]time.Time)}
+}
+
+func (s *MemoryTokenStore) Get(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.values[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return "", nil
+	}
+	return entry.value, nil
+}
+
+func (s *MemoryTokenStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = memoryTokenEntry{value: value, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryTokenStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+func (s *MemoryTokenStore) Lock(ctx context.Context, key string, ttl time.Duration) (unlock func() error, err error) {
+	var expiresAt time.Time
+	err = pollLock(ctx, func() (bool, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if t, ok := s.locks[key]; ok && time.Now().Before(t) {
+			return false, nil
+		}
+		expiresAt = time.Now().Add(ttl)
+		s.locks[key] = expiresAt
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// 锁已过期并被其他调用方获取时不能释放
+		if s.locks[key] == expiresAt {
+			delete(s.locks, key)
+		}
+		return nil
+	}, nil
+}
+
+/* 文件存储 */
+
+// FileTokenStore 文件凭据存储，同一台机器上的多个进程可通过同一目录共享凭据
+type FileTokenStore struct {
+	dir string
+}
+
+type fileTokenEntry struct {
+	Value     string    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewFileTokenStore 创建文件凭据存储，dir 不存在时自动创建
+func NewFileTokenStore(dir string) (*FileTokenStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("wxpay: create token store dir fail, %s", err.Error())
+	}
+	return &FileTokenStore{dir: dir}, nil
+}
+
+func (s *FileTokenStore) path(key string) string {
+	return filepath.Join(s.dir, url.PathEscape(key))
+}
+
+func (s *FileTokenStore) Get(ctx context.Context, key string) (string, error) {
+	b, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	var entry fileTokenEntry
+	if err = json.Unmarshal(b, &entry); err != nil {
+		return "", fmt.Errorf("wxpay: parse token file fail, %s", err.Error())
+	}
+	if time.Now().After(entry.ExpiresAt) {
+		return "", nil
+	}
+	return entry.Value, nil
+}
+
+func (s *FileTokenStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	b, err := json.Marshal(fileTokenEntry{Value: value, ExpiresAt: time.Now().Add(ttl)})
+	if err != nil {
+		return err
+	}
+	// 先写临时文件再重命名，避免其他进程读到不完整的内容
+	f, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), s.path(key))
+}
+
+func (s *FileTokenStore) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// 锁文件内容，owner 为持有者的随机标识，释放时只删除自己持有的锁
+type fileTokenLock struct {
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (s *FileTokenStore) Lock(ctx context.Context, key string, ttl time.Duration) (unlock func() error, err error) {
+	path := s.path(key)
+	b := make([]byte, 16)
+	if _, err = rand.Read(b); err != nil {
+		return nil, err
+	}
+	owner := hex.EncodeToString(b)
+	err = pollLock(ctx, func() (bool, error) {
+		ok, err := s.createLock(path, fileTokenLock{Owner: owner, ExpiresAt: time.Now().Add(ttl)})
+		if ok || err != nil {
+			return ok, err
+		}
+		// 锁已过期视为持有者异常退出，原子地移走过期的锁后重新获取
+		if lock, err := readFileTokenLock(path); err == nil && time.Now().After(lock.ExpiresAt) {
+			if _, err = s.removeLock(path, lock.Owner); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		// 锁已过期并被其他调用方获取时不能释放
+		_, err := s.removeLock(path, owner)
+		return err
+	}, nil
+}
+
+// 创建锁文件，先写临时文件再硬链接到锁文件，锁文件已存在时返回 false，其他进程不会读到不完整的内容
+func (s *FileTokenStore) createLock(path string, lock fileTokenLock) (bool, error) {
+	b, err := json.Marshal(lock)
+	if err != nil {
+		return false, err
+	}
+	f, err := os.CreateTemp(s.dir, ".lock-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(b)
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return false, err
+	}
+	if err = os.Link(f.Name(), path); errors.Is(err, os.ErrExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// 删除 owner 持有的锁文件，先读取校验持有者，再原子地重命名后删除，
+// 读取与重命名之间锁被其他调用方替换时放回原处，无法放回时返回错误
+func (s *FileTokenStore) removeLock(path, owner string) (bool, error) {
+	lock, err := readFileTokenLock(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if lock.Owner != owner {
+		return false, nil
+	}
+	f, err := os.CreateTemp(s.dir, ".unlock-*")
+	if err != nil {
+		return false, err
+	}
+	tmp := f.Name()
+	f.Close()
+	defer os.Remove(tmp)
+	if err = os.Rename(path, tmp); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if lock, err = readFileTokenLock(tmp); err == nil && lock.Owner == owner {
+		return true, nil
+	}
+	if err = os.Link(tmp, path); err != nil {
+		return false, fmt.Errorf("wxpay: restore token lock fail, %s", err.Error())
+	}
+	return false, nil
+}
+
+func readFileTokenLock(path string) (lock fileTokenLock, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(b, &lock)
+	return
+}
@@ -0,0 +1,122 @@
+package wxpay
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 多个客户端共享同一存储并发调用，只刷新一次凭据
+func testTokenStoreShared(t *testing.T, store TokenStore) {
+	ts := newTestTokenServer(t)
+	clients := make([]*Client, 5)
+	for i := range clients {
+		var err error
+		clients[i], err = New(testAppId, testSecret, WithApiHost(ts.URL), WithTokenStore(store))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	callAll := func() {
+		var wg sync.WaitGroup
+		for _, c := range clients {
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func(c *Client) {
+					defer wg.Done()
+					if _, err := c.GetWxACodeUnLimit(context.Background(), GetWxACodeUnLimit{Scene: "id=1"}); err != nil {
+						t.Error(err)
+					}
+				}(c)
+			}
+		}
+		wg.Wait()
+	}
+	callAll()
+	if n := atomic.LoadInt32(&ts.fetches); n != 1 {
+		t.Fatalf("token fetched %d times, want 1", n)
+	}
+	// 凭据失效后所有客户端共用一次刷新结果
+	ts.revoke()
+	callAll()
+	if n := atomic.LoadInt32(&ts.fetches); n != 2 {
+		t.Fatalf("token fetched %d times, want 2", n)
+	}
+}
+
+func TestMemoryTokenStore_Shared(t *testing.T) {
+	testTokenStoreShared(t, NewMemoryTokenStore())
+}
+
+func TestFileTokenStore_Shared(t *testing.T) {
+	store, err := NewFileTokenStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTokenStoreShared(t, store)
+}
+
+func TestFileTokenStore_GetSet(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileTokenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err = store.Set(ctx, "wxpay:access_token:wx", "token", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// 其他进程通过同一目录读取凭据
+	other, err := NewFileTokenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := other.Get(ctx, "wxpay:access_token:wx"); err != nil || v != "token" {
+		t.Fatalf("Get = %q, %v, want token", v, err)
+	}
+	if err = store.Set(ctx, "expired", "token", -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := other.Get(ctx, "expired"); err != nil || v != "" {
+		t.Fatalf("Get expired = %q, %v, want empty", v, err)
+	}
+}
+
+func TestTokenStore_LockExpired(t *testing.T) {
+	fileStore, err := NewFileTokenStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, store := range []TokenStore{NewMemoryTokenStore(), fileStore} {
+		ctx := context.Background()
+		staleUnlock, err := store.Lock(ctx, "lock", 50*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 锁未释放时等待超时
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		if _, err = store.Lock(timeoutCtx, "lock", time.Second); err == nil {
+			t.Fatal("expected lock timeout")
+		}
+		cancel()
+		// 持有者未释放，锁过期后可重新获取
+		unlock, err := store.Lock(ctx, "lock", time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 原持有者释放时不能删除新持有者的锁
+		if err = staleUnlock(); err != nil {
+			t.Fatal(err)
+		}
+		timeoutCtx, cancel = context.WithTimeout(ctx, 10*time.Millisecond)
+		if _, err = store.Lock(timeoutCtx, "lock", time.Second); err == nil {
+			t.Fatal("expected lock held by new owner")
+		}
+		cancel()
+		if err = unlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package wxpay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 模拟小程序凭据接口，每次获取凭据都会使旧凭据失效
+type testTokenServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	token   string
+	fetches int32
+}
+
+func newTestTokenServer(t *testing.T) *testTokenServer {
+	t.Helper()
+	ts := &testTokenServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cgi-bin/token", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&ts.fetches, 1)
+		ts.mu.Lock()
+		ts.token = fmt.Sprintf("token-%d", n)
+		ts.mu.Unlock()
+		json.NewEncoder(w).Encode(GetAccessTokenRsp{AccessToken: ts.token, ExpiresIn: 7200})
+	})
+	mux.HandleFunc("/wxa/getwxacodeunlimit", func(w http.ResponseWriter, r *http.Request) {
+		ts.mu.Lock()
+		valid := r.URL.Query().Get(kFieldAccessToken) == ts.token
+		ts.mu.Unlock()
+		if !valid {
+			json.NewEncoder(w).Encode(AppletError{Errcode: ErrCodeInvalidAccessToken, Errmsg: "invalid credential"})
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("qrcode"))
+	})
+	ts.Server = httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// 使当前凭据失效，模拟其他服务刷新了凭据
+func (ts *testTokenServer) revoke() {
+	ts.mu.Lock()
+	ts.token = "revoked"
+	ts.mu.Unlock()
+}
+
+func TestClient_AccessTokenCached(t *testing.T) {
+	ts := newTestTokenServer(t)
+	c, err := New(testAppId, testSecret, WithApiHost(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := c.GetWxACodeUnLimit(context.Background(), GetWxACodeUnLimit{Scene: "id=1"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if string(r.Buffer) != "qrcode" {
+				t.Errorf("buffer = %s", r.Buffer)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&ts.fetches); n != 1 {
+		t.Fatalf("token fetched %d times, want 1", n)
+	}
+}
+
+func TestClient_AccessTokenInvalidRetry(t *testing.T) {
+	ts := newTestTokenServer(t)
+	c, err := New(testAppId, testSecret, WithApiHost(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.GetWxACodeUnLimit(context.Background(), GetWxACodeUnLimit{Scene: "id=1"}); err != nil {
+		t.Fatal(err)
+	}
+	ts.revoke()
+	// 凭据失效后自动刷新并重试
+	if _, err = c.GetWxACodeUnLimit(context.Background(), GetWxACodeUnLimit{Scene: "id=1"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&ts.fetches); n != 2 {
+		t.Fatalf("token fetched %d times, want 2", n)
+	}
+}
+
+// 发起刷新的调用方取消后，等待同一次刷新的其他调用方仍能拿到凭据
+func TestAccessTokenProvider_LeaderCancel(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		close(started)
+		<-release
+		json.NewEncoder(w).Encode(GetAccessTokenRsp{AccessToken: "token-1", ExpiresIn: 7200})
+	}))
+	t.Cleanup(server.Close)
+	c, err := New(testAppId, testSecret, WithApiHost(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := NewAccessTokenProvider(c)
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := provider.AccessToken(ctx)
+		leaderErr <- err
+	}()
+	<-started
+	waiter := make(chan string, 1)
+	go func() {
+		token, err := provider.AccessToken(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		waiter <- token
+	}()
+	cancel()
+	if err := <-leaderErr; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	close(release)
+	if token := <-waiter; token != "token-1" {
+		t.Fatalf("token = %s, want token-1", token)
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Fatalf("token fetched %d times, want 1", n)
+	}
+}
+
+// 自定义凭据管理返回的凭据始终无效时只重试一次
+type staticTokenProvider struct {
+	invalidated int32
+}
+
+func (p *staticTokenProvider) AccessToken(ctx context.Context) (string, error) {
+	return "static", nil
+}
+
+func (p *staticTokenProvider) Invalidate(ctx context.Context, token string) error {
+	atomic.AddInt32(&p.invalidated, 1)
+	return nil
+}
+
+func TestClient_AccessTokenProvider(t *testing.T) {
+	ts := newTestTokenServer(t)
+	provider := &staticTokenProvider{}
+	c, err := New(testAppId, testSecret, WithApiHost(ts.URL), WithAccessTokenProvider(provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetWxACodeUnLimit(context.Background(), GetWxACodeUnLimit{Scene: "id=1"})
+	aErr, ok := err.(*AppletError)
+	if !ok || !aErr.IsAccessTokenInvalid() {
+		t.Fatalf("expected invalid access token error, got %v", err)
+	}
+	if provider.invalidated != 1 {
+		t.Fatalf("invalidated %d times, want 1", provider.invalidated)
+	}
+}
+
+func TestAccessTokenTTL(t *testing.T) {
+	for expiresIn, want := range map[int]time.Duration{7200: 115 * time.Minute, 600: 5 * time.Minute, 300: 150 * time.Second, 0: 0} {
+		if ttl := accessTokenTTL(expiresIn); ttl != want {
+			t.Errorf("accessTokenTTL(%d) = %s, want %s", expiresIn, ttl, want)
+		}
+	}
+}
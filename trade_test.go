@@ -1,6 +1,7 @@
 package wxpay
 
 import (
+	"context"
 	"testing"
 )
 
@@ -12,15 +13,15 @@ const (
 // 小程序支付
 func TestClient_TradeApplet(t *testing.T) {
 	t.Log("========== TradeApplet ==========")
-	client.LoadOptionFunc(WithPayHost(), WithMchInformation(mchId, mchSecret))
+	skipWithoutClient(t)
 	var p TradeApplet
 	p.Body = "支付测试"
 	p.OutTradeNo = "TEST2023112717521212345678"
-	p.TotalFee = "1"
+	p.TotalFee = 1
 	p.SpbillCreateIp = ""
 	p.OpenId = ""
 	p.NotifyUrl = "https://www.weixin.qq.com/wxpay/pay.php"
-	r, err := client.TradeApplet(p)
+	r, err := client.TradeApplet(context.Background(), p)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -30,14 +31,14 @@ func TestClient_TradeApplet(t *testing.T) {
 // app支付
 func TestClient_TradeApp(t *testing.T) {
 	t.Log("========== TradeApp ==========")
-	client.LoadOptionFunc(WithPayHost(), WithMchInformation(mchId, mchSecret))
+	skipWithoutClient(t)
 	var p TradeApp
 	p.Body = "支付测试"
 	p.OutTradeNo = ""
-	p.TotalFee = "1"
+	p.TotalFee = 1
 	p.SpbillCreateIp = ""
 	p.NotifyUrl = "https://www.weixin.qq.com/wxpay/pay.php"
-	r, err := client.TradeApp(p)
+	r, err := client.TradeApp(context.Background(), p)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -47,15 +48,15 @@ func TestClient_TradeApp(t *testing.T) {
 // 微信内H5支付
 func TestClient_TradeJSAPI(t *testing.T) {
 	t.Log("========== TradeJSAPI ==========")
-	client.LoadOptionFunc(WithPayHost(), WithMchInformation(mchId, mchSecret))
+	skipWithoutClient(t)
 	var p TradeJSAPI
 	p.Body = "支付测试"
 	p.OutTradeNo = "TEST2023112717521212345678"
-	p.TotalFee = "1"
+	p.TotalFee = 1
 	p.SpbillCreateIp = ""
 	p.OpenId = ""
 	p.NotifyUrl = "https://www.weixin.qq.com/wxpay/pay.php"
-	r, err := client.TradeJSAPI(p)
+	r, err := client.TradeJSAPI(context.Background(), p)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -65,10 +66,10 @@ func TestClient_TradeJSAPI(t *testing.T) {
 // 关闭订单
 func TestClient_TradeCloseOrder(t *testing.T) {
 	t.Log("========== TradeCloseOrder ==========")
-	client.LoadOptionFunc(WithApiHost("https://api.mch.weixin.qq.com/pay/closeorder"), WithMchInformation(mchId, mchSecret))
+	skipWithoutClient(t)
 	var p TradeCloseOrder
 	p.OutTradeNo = "TEST2023112717521212345678"
-	r, err := client.TradeCloseOrder(p)
+	r, err := client.TradeCloseOrder(context.Background(), p)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -78,10 +79,10 @@ func TestClient_TradeCloseOrder(t *testing.T) {
 // 查询订单
 func TestClient_TradeOrderQuery(t *testing.T) {
 	t.Log("========== TradeOrderQuery ==========")
-	client.LoadOptionFunc(WithApiHost("https://api.mch.weixin.qq.com/pay/orderquery"), WithMchInformation(mchId, mchSecret))
+	skipWithoutClient(t)
 	var p TradeOrderQuery
 	p.OutTradeNo = "TEST2023112717521212345678"
-	r, err := client.TradeOrderQuery(p)
+	r, err := client.TradeOrderQuery(context.Background(), p)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -91,13 +92,13 @@ func TestClient_TradeOrderQuery(t *testing.T) {
 // 申请退款
 func TestClient_TradeRefund(t *testing.T) {
 	t.Log("========== TradeRefund ==========")
-	client.LoadOptionFunc(WithApiHost("https://api.mch.weixin.qq.com/secapi/pay/refund"), WithMchInformation(mchId, mchSecret))
+	skipWithoutClient(t)
 	var p TradeRefund
 	p.OutTradeNo = "TEST2023112717521212345678"
 	p.OutRefundNo = "TEST2023112717521212345678"
-	p.TotalFee = "1"
-	p.RefundFee = "1"
-	r, err := client.TradeRefund(p)
+	p.TotalFee = 1
+	p.RefundFee = 1
+	r, err := client.TradeRefund(context.Background(), p)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -107,10 +108,10 @@ func TestClient_TradeRefund(t *testing.T) {
 // 查询退款
 func TestClient_TradeRefundQuery(t *testing.T) {
 	t.Log("========== TradeRefundQuery ==========")
-	client.LoadOptionFunc(WithApiHost("https://api.mch.weixin.qq.com/pay/refundquery"), WithMchInformation(mchId, mchSecret))
+	skipWithoutClient(t)
 	var p TradeRefundQuery
 	p.OutRefundNo = "TEST2023112717521212345678"
-	r, err := client.TradeRefundQuery(p)
+	r, err := client.TradeRefundQuery(context.Background(), p)
 	if err != nil {
 		t.Fatal(err)
 	}
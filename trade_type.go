@@ -1,13 +1,55 @@
 package wxpay
 
+import "time"
+
+// 交易状态
+const (
+	TradeStateSuccess    = "SUCCESS"    // 支付成功
+	TradeStateRefund     = "REFUND"     // 转入退款
+	TradeStateNotPay     = "NOTPAY"     // 未支付
+	TradeStateClosed     = "CLOSED"     // 已关闭
+	TradeStateRevoked    = "REVOKED"    // 已撤销（付款码支付）
+	TradeStateUserPaying = "USERPAYING" // 用户支付中（付款码支付）
+	TradeStatePayError   = "PAYERROR"   // 支付失败（其他原因，如银行返回失败）
+)
+
+// 支付接口业务错误码
+const (
+	PayErrCodeSystemError   = "SYSTEMERROR"       // 系统超时，需查询订单确认结果
+	PayErrCodeBankError     = "BANKERROR"         // 银行端超时，需查询订单确认结果
+	PayErrCodeUserPaying    = "USERPAYING"        // 用户支付中，需要输入密码
+	PayErrCodeOrderNotExist = "ORDERNOTEXIST"     // 此交易订单号不存在
+	PayErrCodeBizNeedRetry  = "BIZERR_NEED_RETRY" // 退款业务流程错误，需要商户使用相同的退款单号重试
+)
+
+// SubMerchant 服务商模式下的子商户参数，服务商使用自身的 appid、mch_id 创建客户端，每次调用时传入子商户参数
+type SubMerchant struct {
+	SubAppId string `xml:"sub_appid,omitempty" json:"sub_appid,omitempty"`   // 微信分配的子商户公众账号ID，如需在支付完成后获取sub_openid则此参数必传
+	SubMchId string `xml:"sub_mch_id,omitempty" json:"sub_mch_id,omitempty"` // 微信支付分配的子商户号
+}
+
+// 传入子商户公众账号ID时必须同时传入子商户号
+func (s SubMerchant) validate(v *validator) {
+	if s.SubAppId != "" {
+		v.required("sub_mch_id", s.SubMchId)
+	}
+}
+
+// SubMerchantRsp 服务商模式下返回的子商户参数
+type SubMerchantRsp struct {
+	SubAppId string `xml:"sub_appid,omitempty" json:"sub_appid,omitempty"`   // 微信分配的子商户公众账号ID
+	SubMchId string `xml:"sub_mch_id,omitempty" json:"sub_mch_id,omitempty"` // 微信支付分配的子商户号
+}
+
 // 请求参数
 type Trade struct {
 	AuxParam
+	SubMerchant
 	NotifyUrl string `xml:"notify_url" json:"notify_url"` // 接收微信支付异步通知回调地址，通知url必须为直接可访问的url，不能携带参数。公网域名必须为https，如果是走专线接入，使用专线NAT IP或者私有回调域名可使用http。
 	// 必填，主要参数
 	Body           string `xml:"body" json:"body"`                         // 商品描述交易字段格式根据不同的应用场景按照以下格式： APP——需传入应用市场上的APP名字-实际商品名称，天天爱消除-游戏充值。
 	OutTradeNo     string `xml:"out_trade_no" json:"out_trade_no"`         // 商户系统内部订单号，要求32个字符内（最少6个字符），只能是数字、大小写字母_-|*且在同一个商户号下唯一。
-	TotalFee       string `xml:"total_fee" json:"total_fee"`               // 订单总金额，单位为分
+	TotalFee       Fen    `xml:"total_fee" json:"total_fee"`               // 订单总金额，单位为分
 	SpbillCreateIp string `xml:"spbill_create_ip" json:"spbill_create_ip"` // 支持IPV4和IPV6两种格式的IP地址。调用微信支付API的机器IP
 	TradeType      string `xml:"trade_type" json:"trade_type"`             // 支付类型
 	// 选填，额外参数
@@ -25,6 +67,45 @@ type Trade struct {
 	SceneInfo     string `xml:"scene_info,omitempty" json:"scene_info,omitempty"`         // 场景信息，WAP支付必填，该字段常用于线下活动时的场景信息上报，支持上报实际门店信息，商户也可以按需求自己上报相关信息。该字段为JSON对象数据，对象格式为{"store_info":{"id": "门店ID","name": "名称","area_code": "编码","address": "地址" }}
 }
 
+func (t Trade) ApiPath() string {
+	return "/pay/unifiedorder"
+}
+
+func (t Trade) Validate() error {
+	var v validator
+	t.validate(&v)
+	return v.err()
+}
+
+// 统一下单公共参数校验
+func (t Trade) validate(v *validator) {
+	t.SubMerchant.validate(v)
+	if v.required("body", t.Body) {
+		v.maxLen("body", t.Body, 128)
+	}
+	v.outTradeNo(t.OutTradeNo)
+	v.amount("total_fee", t.TotalFee)
+	if v.required("spbill_create_ip", t.SpbillCreateIp) {
+		v.ip("spbill_create_ip", t.SpbillCreateIp)
+	}
+	if v.required("notify_url", t.NotifyUrl) {
+		v.notifyUrl(t.NotifyUrl)
+	}
+	v.maxLen("attach", t.Attach, 127)
+	v.maxLen("product_id", t.ProductId, 32)
+	var start, expire time.Time
+	var startOk, expireOk bool
+	if t.TimeStart != "" {
+		start, startOk = v.tradeTime("time_start", t.TimeStart)
+	}
+	if t.TimeExpire != "" {
+		expire, expireOk = v.tradeTime("time_expire", t.TimeExpire)
+	}
+	if startOk && expireOk && expire.Sub(start) < kTradeMinExpireGap {
+		v.add("time_expire", "must be at least 5 minutes after time_start")
+	}
+}
+
 // TradeSceneInfo 场景信息
 type TradeSceneInfo struct {
 	Id       string `json:"id"`        // 门店编号，由商户自定义
@@ -35,6 +116,7 @@ type TradeSceneInfo struct {
 
 type TradeResponse struct {
 	PayError
+	SubMerchantRsp
 	AppID      string `xml:"appid" json:"appid"`                       // 调用接口提交的公众账号ID
 	MchID      string `xml:"mch_id" json:"mch_id"`                     // 调用接口提交的商户号
 	NonceStr   string `xml:"nonce_str" json:"nonce_str"`               // 微信返回的随机字符串
@@ -49,13 +131,25 @@ type TradeResponse struct {
 // TradeApplet 小程序统一下单接口 https://pay.weixin.qq.com/wiki/doc/api/wxa/wxa_api.php?chapter=9_1
 type TradeApplet struct {
 	Trade
-	OpenId string `xml:"openid" json:"openid"` // trade_type=JSAPI，此参数必传，用户在商户appid下的唯一标识。
+	OpenId    string `xml:"openid" json:"openid"`                             // trade_type=JSAPI，此参数必传，用户在商户appid下的唯一标识。
+	SubOpenId string `xml:"sub_openid,omitempty" json:"sub_openid,omitempty"` // 用户在子商户appid下的唯一标识，传入sub_openid时sub_appid必传，openid和sub_openid可以选传其中之一
 }
 
 func (t TradeApplet) ReturnType() string {
 	return "xml"
 }
 
+func (t TradeApplet) Validate() error {
+	var v validator
+	t.validate(&v)
+	if t.SubOpenId != "" {
+		v.required("sub_appid", t.SubAppId)
+	} else {
+		v.required("openid", t.OpenId)
+	}
+	return v.err()
+}
+
 // TradeAppletRsp 小程序统一下单响应参数
 type TradeAppletRsp struct {
 	TradeResponse
@@ -92,13 +186,25 @@ type TradeAppRsp struct {
 // TradeJSAPI 微信内H5统一下单接口 https://pay.weixin.qq.com/wiki/doc/api/jsapi.php?chapter=9_1
 type TradeJSAPI struct {
 	Trade
-	OpenId string `xml:"openid" json:"openid"` // trade_type=JSAPI时（即JSAPI支付），此参数必传，此参数为微信用户在商户对应appid下的唯一标识。openid如何获取
+	OpenId    string `xml:"openid" json:"openid"`                             // trade_type=JSAPI时（即JSAPI支付），此参数必传，此参数为微信用户在商户对应appid下的唯一标识。openid如何获取
+	SubOpenId string `xml:"sub_openid,omitempty" json:"sub_openid,omitempty"` // 用户在子商户appid下的唯一标识，传入sub_openid时sub_appid必传，openid和sub_openid可以选传其中之一
 }
 
 func (t TradeJSAPI) ReturnType() string {
 	return "xml"
 }
 
+func (t TradeJSAPI) Validate() error {
+	var v validator
+	t.validate(&v)
+	if t.SubOpenId != "" {
+		v.required("sub_appid", t.SubAppId)
+	} else {
+		v.required("openid", t.OpenId)
+	}
+	return v.err()
+}
+
 // TradeJSAPIRsp TradeJSAPI 微信内H5统一下单响应参数
 type TradeJSAPIRsp struct {
 	TradeResponse
@@ -116,6 +222,13 @@ func (t TradeNative) ReturnType() string {
 	return "xml"
 }
 
+func (t TradeNative) Validate() error {
+	var v validator
+	t.validate(&v)
+	v.required("product_id", t.ProductId)
+	return v.err()
+}
+
 // TradeNativeRsp Native统一下单接口响应参数
 type TradeNativeRsp struct {
 	TradeResponse
@@ -133,6 +246,13 @@ func (t TradeWap) ReturnType() string {
 	return "xml"
 }
 
+func (t TradeWap) Validate() error {
+	var v validator
+	t.validate(&v)
+	v.required("scene_info", t.SceneInfo)
+	return v.err()
+}
+
 type SceneInfo struct {
 	H5Info struct {
 		Type        string `json:"type"`
@@ -161,6 +281,7 @@ type TradeWapRsp struct {
 // TradeOrderQuery 查询订单 https://pay.weixin.qq.com/wiki/doc/api/wxa/wxa_api.php?chapter=9_2
 type TradeOrderQuery struct {
 	AuxParam
+	SubMerchant
 	OutTradeNo    string `xml:"out_trade_no,omitempty" json:"out_trade_no,omitempty"`     // 商户系统内部订单号，要求32个字符内（最少6个字符），只能是数字、大小写字母_-|*且在同一个商户号下唯一。
 	TransactionId string `xml:"transaction_id,omitempty" json:"transaction_id,omitempty"` // 微信的订单号，建议优先使用
 }
@@ -169,25 +290,45 @@ func (t TradeOrderQuery) ReturnType() string {
 	return "xml"
 }
 
+func (t TradeOrderQuery) ApiPath() string {
+	return "/pay/orderquery"
+}
+
+// 查询订单可以使用相同的请求内容重试
+func (t TradeOrderQuery) Idempotent() bool {
+	return true
+}
+
+func (t TradeOrderQuery) Validate() error {
+	var v validator
+	t.SubMerchant.validate(&v)
+	if t.TransactionId == "" && v.required("out_trade_no", t.OutTradeNo) {
+		v.outTradeNo(t.OutTradeNo)
+	}
+	return v.err()
+}
+
 // TradeOrderQueryRsp 查询订单响应参数
 type TradeOrderQueryRsp struct {
 	PayError
+	SubMerchantRsp
 	DeviceInfo         string `xml:"device_info" json:"device_info"`                             // 微信支付分配的终端设备号
 	OpenId             string `xml:"openid" json:"openid"`                                       // 用户在商户appid下的唯一标识
+	SubOpenId          string `xml:"sub_openid,omitempty" json:"sub_openid,omitempty"`           // 用户在子商户appid下的唯一标识，服务商模式下返回
 	IsSubscribe        string `xml:"is_subscribe" json:"is_subscribe"`                           // 已废弃，默认统一返回N
 	TradeType          string `xml:"trade_type" json:"trade_type"`                               // 调用接口提交的交易类型，取值如下：JSAPI，NATIVE，APP，MICROPAY
 	TradeState         string `xml:"trade_state" json:"trade_state"`                             // SUCCESS--支付成功 REFUND--转入退款 NOTPAY--未支付 CLOSED--已关闭 REVOKED--已撤销(刷卡支付) USERPAYING--用户支付中 PAYERROR--支付失败(其他原因，如银行返回失败) ACCEPT--已接收，等待扣款
 	BankType           string `xml:"bank_type" json:"bank_type"`                                 // 银行类型，采用字符串类型的银行标识
-	TotalFee           int    `xml:"total_fee" json:"total_fee"`                                 // 订单总金额，单位为分
-	SettlementTotalFee int    `xml:"settlement_total_fee,omitempty" json:"settlement_total_fee"` // 当订单使用了免充值型优惠券后返回该参数，应结订单金额=订单金额-免充值优惠券金额。
+	TotalFee           Fen    `xml:"total_fee" json:"total_fee"`                                 // 订单总金额，单位为分
+	SettlementTotalFee Fen    `xml:"settlement_total_fee,omitempty" json:"settlement_total_fee"` // 当订单使用了免充值型优惠券后返回该参数，应结订单金额=订单金额-免充值优惠券金额。
 	FeeType            string `xml:"fee_type" json:"fee_type"`                                   // 货币类型，符合ISO 4217标准的三位字母代码，默认人民币：CNY
-	CashFee            int    `xml:"cash_fee,omitempty" json:"cash_fee"`                         // 现金支付金额订单现金支付金额
+	CashFee            Fen    `xml:"cash_fee,omitempty" json:"cash_fee"`                         // 现金支付金额订单现金支付金额
 	CashFeeType        string `xml:"cash_fee_type,omitempty" json:"cash_fee_type"`               // 货币类型，符合ISO 4217标准的三位字母代码，默认人民币：CNY
-	CouponFee          int    `xml:"coupon_fee,omitempty" json:"coupon_fee"`                     // “代金券”金额<=订单金额，订单金额-“代金券”金额=现金支付金额
+	CouponFee          Fen    `xml:"coupon_fee,omitempty" json:"coupon_fee"`                     // “代金券”金额<=订单金额，订单金额-“代金券”金额=现金支付金额
 	CouponCount        int    `xml:"coupon_count,omitempty" json:"coupon_count"`                 // 代金券使用数量
 	CouponType0        string `xml:"coupon_type_0,omitempty" json:"coupon_type_0"`               // CASH：充值代金券 NO_CASH：非充值优惠券 开通免充值券功能，并且订单使用了优惠券后有返回（取值：CASH、NO_CASH）。$n为下标,从0开始编号，举例：coupon_type_$0
 	CouponId0          string `xml:"coupon_id_0,omitempty" json:"coupon_id_0"`                   // 代金券ID, $n为下标，从0开始编号
-	CouponFee0         string `xml:"coupon_fee_0,omitempty" json:"coupon_fee_0"`                 // 单个代金券支付金额, $n为下标，从0开始编号
+	CouponFee0         Fen    `xml:"coupon_fee_0,omitempty" json:"coupon_fee_0"`                 // 单个代金券支付金额, $n为下标，从0开始编号
 	TransactionId      string `xml:"transaction_id" json:"transaction_id"`                       // 微信支付订单号
 	OutTradeNo         string `xml:"out_trade_no" json:"out_trade_no"`                           // 商户系统内部订单号，要求32个字符内（最少6个字符），只能是数字、大小写字母_-|*且在同一个商户号下唯一
 	Attach             string `xml:"attach" json:"attach"`                                       // 附加数据，原样返回
@@ -200,6 +341,7 @@ type TradeOrderQueryRsp struct {
 // TradeCloseOrder 关闭订单 https://pay.weixin.qq.com/wiki/doc/api/wxa/wxa_api.php?chapter=9_3
 type TradeCloseOrder struct {
 	AuxParam
+	SubMerchant
 	OutTradeNo string `xml:"out_trade_no" json:"out_trade_no"` // 商户系统内部订单号，要求32个字符内（最少6个字符），只能是数字、大小写字母_-|*且在同一个商户号下唯一
 }
 
@@ -207,9 +349,26 @@ func (t TradeCloseOrder) ReturnType() string {
 	return "xml"
 }
 
+func (t TradeCloseOrder) ApiPath() string {
+	return "/pay/closeorder"
+}
+
+// 关闭订单可以使用相同的请求内容重试
+func (t TradeCloseOrder) Idempotent() bool {
+	return true
+}
+
+func (t TradeCloseOrder) Validate() error {
+	var v validator
+	t.SubMerchant.validate(&v)
+	v.outTradeNo(t.OutTradeNo)
+	return v.err()
+}
+
 // TradeCloseOrderRsp 关闭订单响应参数
 type TradeCloseOrderRsp struct {
 	PayError
+	SubMerchantRsp
 	AppID     string `xml:"appid" json:"appid"`           // 微信分配的公众账号ID
 	MchID     string `xml:"mch_id" json:"mch_id"`         // 微信支付分配的商户号
 	NonceStr  string `xml:"nonce_str" json:"nonce_str"`   // 随机字符串，不长于32位
@@ -222,11 +381,12 @@ type TradeCloseOrderRsp struct {
 // TradeRefund 申请退款 https://pay.weixin.qq.com/wiki/doc/api/wxa/wxa_api.php?chapter=9_4
 type TradeRefund struct {
 	AuxParam
+	SubMerchant
 	OutTradeNo    string `xml:"out_trade_no,omitempty" json:"out_trade_no,omitempty"`       // 商户系统内部订单号，要求32个字符内（最少6个字符），只能是数字、大小写字母_-|*且在同一个商户号下唯一。transaction_id、out_trade_no二选一，如果同时存在优先级：transaction_id > out_trade_no
 	TransactionId string `xml:"transaction_id,omitempty" json:"transaction_id,omitempty"`   // 微信生成的订单号，在支付通知中有返回
 	OutRefundNo   string `xml:"out_refund_no" json:"out_refund_no"`                         // 商户系统内部的退款单号，商户系统内部唯一，只能是数字、大小写字母_-|*@ ，同一退款单号多次请求只退一笔。
-	TotalFee      string `xml:"total_fee" json:"total_fee"`                                 // 订单总金额，单位为分，只能为整数
-	RefundFee     string `xml:"refund_fee" json:"refund_fee"`                               // 退款总金额，订单总金额，单位为分，只能为整数
+	TotalFee      Fen    `xml:"total_fee" json:"total_fee"`                                 // 订单总金额，单位为分，只能为整数
+	RefundFee     Fen    `xml:"refund_fee" json:"refund_fee"`                               // 退款总金额，订单总金额，单位为分，只能为整数
 	RefundFeeType string `xml:"refund_fee_type,omitempty" json:"refund_fee_type,omitempty"` // 退款货币类型，需与支付一致，或者不填。符合ISO 4217标准的三位字母代码，默认人民币：CNY
 	RefundDesc    string `xml:"refund_desc" json:"refund_desc"`                             // 退款原因，若商户传入，会在下发给用户的退款消息中体现退款原因
 	RefundAccount string `xml:"refund_account,omitempty" json:"refund_account,omitempty"`   // 退款资金来源，仅针对老资金流商户使用 REFUND_SOURCE_UNSETTLED_FUNDS---未结算资金退款（默认使用未结算资金退款） REFUND_SOURCE_RECHARGE_FUNDS---可用余额退款
@@ -241,9 +401,38 @@ func (t TradeRefund) ReturnType() string {
 	return "xml"
 }
 
+func (t TradeRefund) ApiPath() string {
+	return "/secapi/pay/refund"
+}
+
+// 申请退款使用相同的 out_refund_no 重试只会退款一笔，可以使用相同的请求内容重试
+func (t TradeRefund) Idempotent() bool {
+	return true
+}
+
+func (t TradeRefund) Validate() error {
+	var v validator
+	t.SubMerchant.validate(&v)
+	if t.TransactionId == "" && v.required("out_trade_no", t.OutTradeNo) {
+		v.outTradeNo(t.OutTradeNo)
+	}
+	v.outRefundNo(t.OutRefundNo)
+	v.amount("total_fee", t.TotalFee)
+	v.amount("refund_fee", t.RefundFee)
+	if t.RefundFee > t.TotalFee {
+		v.add("refund_fee", "must not exceed total_fee")
+	}
+	v.maxLen("refund_desc", t.RefundDesc, 80)
+	if t.NotifyUrl != "" {
+		v.notifyUrl(t.NotifyUrl)
+	}
+	return v.err()
+}
+
 // TradeRefundRsp 申请退款响应参数
 type TradeRefundRsp struct {
 	PayError
+	SubMerchantRsp
 	AppID               string `xml:"appid" json:"appid"`                                   // 微信分配的公众账号ID
 	MchID               string `xml:"mch_id" json:"mch_id"`                                 // 微信支付分配的商户号
 	NonceStr            string `xml:"nonce_str" json:"nonce_str"`                           // 随机字符串，不长于32位
@@ -252,17 +441,17 @@ type TradeRefundRsp struct {
 	OutTradeNo          string `xml:"out_trade_no" json:"out_trade_no"`                     // 商户系统内部订单号，要求32个字符内（最少6个字符），只能是数字、大小写字母_-|*且在同一个商户号下唯一。
 	OutRefundNo         string `xml:"out_refund_no" json:"out_refund_no"`                   // 商户系统内部的退款单号，商户系统内部唯一，只能是数字、大小写字母_-|*@ ，同一退款单号多次请求只退一笔。
 	RefundId            string `xml:"refund_id" json:"refund_id"`                           // 微信退款单号
-	RefundFee           int    `xml:"refund_fee" json:"refund_fee"`                         // 退款总金额，单位为分，可以做部分退款
-	SettlementRefundFee int    `xml:"settlement_refund_fee" json:"settlement_refund_fee"`   // 应结退款金额，去掉非充值代金券退款金额后的退款金额，退款金额=申请退款金额-非充值代金券退款金额，退款金额<=申请退款金额
-	TotalFee            int    `xml:"total_fee" json:"total_fee"`                           // 订单总金额，单位为分，只能为整数
-	SettlementTotalFee  int    `xml:"settlement_total_fee" json:"settlement_total_fee"`     // 应结订单金额，去掉非充值代金券金额后的订单总金额，应结订单金额=订单金额-非充值代金券金额，应结订单金额<=订单金额。
+	RefundFee           Fen    `xml:"refund_fee" json:"refund_fee"`                         // 退款总金额，单位为分，可以做部分退款
+	SettlementRefundFee Fen    `xml:"settlement_refund_fee" json:"settlement_refund_fee"`   // 应结退款金额，去掉非充值代金券退款金额后的退款金额，退款金额=申请退款金额-非充值代金券退款金额，退款金额<=申请退款金额
+	TotalFee            Fen    `xml:"total_fee" json:"total_fee"`                           // 订单总金额，单位为分，只能为整数
+	SettlementTotalFee  Fen    `xml:"settlement_total_fee" json:"settlement_total_fee"`     // 应结订单金额，去掉非充值代金券金额后的订单总金额，应结订单金额=订单金额-非充值代金券金额，应结订单金额<=订单金额。
 	FeeType             string `xml:"fee_type" json:"fee_type"`                             // 标价币种，订单金额货币类型，符合ISO 4217标准的三位字母代码，默认人民币：CNY
-	CashFee             int    `xml:"cash_fee" json:"cash_fee"`                             // 现金支付金额，单位为分，只能为整数
+	CashFee             Fen    `xml:"cash_fee" json:"cash_fee"`                             // 现金支付金额，单位为分，只能为整数
 	CashFeeType         string `xml:"cash_fee_type" json:"cash_fee_type"`                   // 现金支付币种，货币类型，符合ISO 4217标准的三位字母代码，默认人民币：CNY
-	CashRefundFee       int    `xml:"cash_refund_fee" json:"cash_refund_fee"`               // 现金退款金额，单位为分，只能为整数
+	CashRefundFee       Fen    `xml:"cash_refund_fee" json:"cash_refund_fee"`               // 现金退款金额，单位为分，只能为整数
 	CouponType0         string `xml:"coupon_type_0,omitempty" json:"coupon_type_0"`         // 代金券类型，CASH--充值代金券 NO_CASH---非充值代金券 订单使用代金券时有返回（取值：CASH、NO_CASH）。$n为下标,从0开始编号，举例：coupon_type_0
-	CouponRefundFee     int    `xml:"coupon_refund_fee,omitempty" json:"coupon_refund_fee"` // 代金券退款总金额，代金券退款金额<=退款金额，退款金额-代金券或立减优惠退款金额为现金
-	CouponRefundFee0    int    `xml:"coupon_refund_fee_0" json:"coupon_refund_fee_0"`       // 单个代金券退款金额，代金券退款金额<=退款金额，退款金额-代金券或立减优惠退款金额为现金
+	CouponRefundFee     Fen    `xml:"coupon_refund_fee,omitempty" json:"coupon_refund_fee"` // 代金券退款总金额，代金券退款金额<=退款金额，退款金额-代金券或立减优惠退款金额为现金
+	CouponRefundFee0    Fen    `xml:"coupon_refund_fee_0" json:"coupon_refund_fee_0"`       // 单个代金券退款金额，代金券退款金额<=退款金额，退款金额-代金券或立减优惠退款金额为现金
 	CouponRefundCount   int    `xml:"coupon_refund_count" json:"coupon_refund_count"`       // 退款代金券使用数量
 	CouponRefundId0     string `xml:"coupon_refund_id_0" json:"coupon_refund_id_0"`         // 退款代金券ID, $n为下标，从0开始编号
 }
@@ -272,6 +461,7 @@ type TradeRefundRsp struct {
 // TradeRefundQuery 查询退款 https://pay.weixin.qq.com/wiki/doc/api/wxa/wxa_api.php?chapter=9_5
 type TradeRefundQuery struct {
 	AuxParam
+	SubMerchant
 	TransactionId string `xml:"transaction_id,omitempty" json:"transaction_id,omitempty"` // 微信订单号查询的优先级是： refund_id > out_refund_no > transaction_id > out_trade_no
 	OutTradeNo    string `xml:"out_trade_no,omitempty" json:"out_trade_no,omitempty"`     // 商户系统内部订单号，要求32个字符内（最少6个字符），只能是数字、大小写字母_-|*且在同一个商户号下唯一。
 	OutRefundNo   string `xml:"out_refund_no,omitempty" json:"out_refund_no,omitempty"`   // 商户系统内部的退款单号，商户系统内部唯一，只能是数字、大小写字母_-|*@ ，同一退款单号多次请求只退一笔。
@@ -283,9 +473,28 @@ func (t TradeRefundQuery) ReturnType() string {
 	return "xml"
 }
 
+func (t TradeRefundQuery) ApiPath() string {
+	return "/pay/refundquery"
+}
+
+// 查询退款可以使用相同的请求内容重试
+func (t TradeRefundQuery) Idempotent() bool {
+	return true
+}
+
+func (t TradeRefundQuery) Validate() error {
+	var v validator
+	t.SubMerchant.validate(&v)
+	if t.TransactionId == "" && t.OutTradeNo == "" && t.OutRefundNo == "" && t.RefundId == "" {
+		v.add("refund_id", "one of refund_id, out_refund_no, transaction_id, out_trade_no is required")
+	}
+	return v.err()
+}
+
 // TradeRefundQueryRsp 查询退款响应参数
 type TradeRefundQueryRsp struct {
 	PayError
+	SubMerchantRsp
 	AppID                string `xml:"appid" json:"appid"`                                               // 微信分配的公众账号ID（企业号corpid即为此appid）
 	MchID                string `xml:"mch_id" json:"mch_id"`                                             // 微信支付分配的商户号
 	NonceStr             string `xml:"nonce_str" json:"nonce_str"`                                       // 随机字符串，不长于32位
@@ -293,26 +502,182 @@ type TradeRefundQueryRsp struct {
 	TotalRefundCount     int    `xml:"total_refund_count" json:"total_refund_count"`                     // 订单总共已发生的部分退款次数，当请求参数传入offset后有返回
 	TransactionId        string `xml:"transaction_id" json:"transaction_id"`                             // 微信订单号
 	OutTradeNo           string `xml:"out_trade_no" json:"out_trade_no"`                                 // 商户系统内部订单号，要求32个字符内（最少6个字符），只能是数字、大小写字母_-|*且在同一个商户号下唯一。
-	TotalFee             int    `xml:"total_fee" json:"total_fee"`                                       // 订单总金额，单位为分，只能为整数
-	SettlementTotalFee   int    `xml:"settlement_total_fee" json:"settlement_total_fee"`                 // 应结订单金额，当订单使用了免充值型优惠券后返回该参数，应结订单金额=订单金额-免充值优惠券金额。
+	TotalFee             Fen    `xml:"total_fee" json:"total_fee"`                                       // 订单总金额，单位为分，只能为整数
+	SettlementTotalFee   Fen    `xml:"settlement_total_fee" json:"settlement_total_fee"`                 // 应结订单金额，当订单使用了免充值型优惠券后返回该参数，应结订单金额=订单金额-免充值优惠券金额。
 	FeeType              string `xml:"fee_type" json:"fee_type"`                                         // 货币种类，订单金额货币类型，符合ISO 4217标准的三位字母代码，默认人民币：CNY
-	CashFee              int    `xml:"cash_fee" json:"cash_fee"`                                         // 现金支付金额，单位为分，只能为整数
+	CashFee              Fen    `xml:"cash_fee" json:"cash_fee"`                                         // 现金支付金额，单位为分，只能为整数
 	RefundCount          int    `xml:"refund_count" json:"refund_count"`                                 // 当前返回退款笔数
 	OutRefundNo0         string `xml:"out_refund_no_0,omitempty" json:"out_refund_no_0"`                 // 商户系统内部的退款单号，商户系统内部唯一，只能是数字、大小写字母_-|*@ ，同一退款单号多次请求只退一笔。
 	RefundId0            string `xml:"refund_id_0,omitempty" json:"refund_id_0"`                         // 微信退款单号
 	RefundChannel0       string `xml:"refund_channel_0,omitempty" json:"refund_channel_0"`               // 退款渠道 ORIGINAL—原路退款 BALANCE—退回到余额 OTHER_BALANCE—原账户异常退到其他余额账户 OTHER_BANKCARD—原银行卡异常退到其他银行卡
-	RefundFee0           int    `xml:"refund_fee_0,omitempty" json:"refund_fee_0"`                       // 申请退款金额，退款总金额，单位为分，可以做部分退款
-	RefundFee            int    `xml:"refund_fee" json:"refund_fee"`                                     // 退款总金额，各退款单的退款金额累加
-	CouponRefundFee      int    `xml:"coupon_refund_fee" json:"coupon_refund_fee"`                       // 代金券退款总金额，各退款单的代金券退款金额累加
-	SettlementRefundFee0 int    `xml:"settlement_refund_fee_0,omitempty" json:"settlement_refund_fee_0"` // 退款金额，退款金额=申请退款金额-非充值代金券退款金额，退款金额<=申请退款金额
+	RefundFee0           Fen    `xml:"refund_fee_0,omitempty" json:"refund_fee_0"`                       // 申请退款金额，退款总金额，单位为分，可以做部分退款
+	RefundFee            Fen    `xml:"refund_fee" json:"refund_fee"`                                     // 退款总金额，各退款单的退款金额累加
+	CouponRefundFee      Fen    `xml:"coupon_refund_fee" json:"coupon_refund_fee"`                       // 代金券退款总金额，各退款单的代金券退款金额累加
+	SettlementRefundFee0 Fen    `xml:"settlement_refund_fee_0,omitempty" json:"settlement_refund_fee_0"` // 退款金额，退款金额=申请退款金额-非充值代金券退款金额，退款金额<=申请退款金额
 	CouponType00         string `xml:"coupon_type_0_0,omitempty" json:"coupon_type_00"`                  // 代金券类型，CASH--充值代金券 NO_CASH---非充值优惠券 开通免充值券功能，并且订单使用了优惠券后有返回（取值：CASH、NO_CASH）。$n为下标,$m为下标,从0开始编号，举例：coupon_type_$0_$1
-	CouponRefundFee0     int    `xml:"coupon_refund_fee_0,omitempty" json:"coupon_refund_fee_0"`         // 总代金券退款金额，代金券退款金额<=退款金额，退款金额-代金券或立减优惠退款金额为现金
+	CouponRefundFee0     Fen    `xml:"coupon_refund_fee_0,omitempty" json:"coupon_refund_fee_0"`         // 总代金券退款金额，代金券退款金额<=退款金额，退款金额-代金券或立减优惠退款金额为现金
 	CouponRefundCount0   int    `xml:"coupon_refund_count_0,omitempty" json:"coupon_refund_count_0"`     // 退款代金券使用数量 ,$n为下标,从0开始编号
 	CouponRefundId00     string `xml:"coupon_refund_id_0_0,omitempty" json:"coupon_refund_id_00"`        // 退款代金券ID, $n为下标，$m为下标，从0开始编号
-	CouponRefundFee00    int    `xml:"coupon_refund_fee_0_0,omitempty" json:"coupon_refund_fee_00"`      // 单个退款代金券支付金额, $n为下标，$m为下标，从0开始编号
+	CouponRefundFee00    Fen    `xml:"coupon_refund_fee_0_0,omitempty" json:"coupon_refund_fee_00"`      // 单个退款代金券支付金额, $n为下标，$m为下标，从0开始编号
 	RefundStatus0        string `xml:"refund_status_0,omitempty" json:"refund_status_0"`                 // 退款状态： SUCCESS—退款成功 REFUNDCLOSE—退款关闭，指商户发起退款失败的情况。 PROCESSING—退款处理中 CHANGE—退款异常，退款到银行发现用户的卡作废或者冻结了，导致原路退款银行卡失败，可前往商户平台（pay.weixin.qq.com）-交易中心，手动处理此笔退款。$n为下标，从0开始编号。
 	RefundAccount0       string `xml:"refund_account_0,omitempty" json:"refund_account_0"`               // 退款资金来源，REFUND_SOURCE_RECHARGE_FUNDS---可用余额退款/基本账户 REFUND_SOURCE_UNSETTLED_FUNDS---未结算资金退款 $n为下标，从0开始编号
 	RefundRecvAccout0    string `xml:"refund_recv_accout_0,omitempty" json:"refund_recv_accout_0"`       // 退款入账账户，取当前退款单的退款入账方 1）退回银行卡： {银行名称}{卡类型}{卡尾号} 2）退回支付用户零钱: 支付用户零钱 3）退还商户: 商户基本账户 商户结算银行账户 4）退回支付用户零钱通: 支付用户零钱通
 	RefundSuccessTime0   string `xml:"refund_success_time_0,omitempty" json:"refund_success_time_0"`     // 退款成功时间，当退款状态为退款成功时有返回。$n为下标，从0开始编号。
-	CashRefundFee        int    `xml:"cash_refund_fee" json:"cash_refund_fee"`                           // 用户退款金额，退款给用户的金额，不包含所有优惠券金额
+	CashRefundFee        Fen    `xml:"cash_refund_fee" json:"cash_refund_fee"`                           // 用户退款金额，退款给用户的金额，不包含所有优惠券金额
+}
+
+/* 付款码支付 */
+
+// TradeMicropay 付款码支付 https://pay.weixin.qq.com/wiki/doc/api/micropay.php?chapter=9_10&index=1
+type TradeMicropay struct {
+	AuxParam
+	SubMerchant
+	// 必填，主要参数
+	Body           string `xml:"body" json:"body"`                         // 商品简单描述，该字段须严格按照规范传递
+	OutTradeNo     string `xml:"out_trade_no" json:"out_trade_no"`         // 商户系统内部订单号，要求32个字符内（最少6个字符），只能是数字、大小写字母_-|*且在同一个商户号下唯一。
+	TotalFee       Fen    `xml:"total_fee" json:"total_fee"`               // 订单总金额，单位为分，只能为整数
+	SpbillCreateIp string `xml:"spbill_create_ip" json:"spbill_create_ip"` // 支持IPV4和IPV6两种格式的IP地址。调用微信支付API的机器IP
+	AuthCode       string `xml:"auth_code" json:"auth_code"`               // 扫码支付付款码，设备读取用户微信中的条码或者二维码信息，用户付款码规则：18位纯数字，前缀以10、11、12、13、14、15开头
+	// 选填，额外参数
+	Attach        string `xml:"attach,omitempty" json:"attach,omitempty"`                 // 附加数据，在查询API和支付通知中原样返回，该字段主要用于商户携带订单的自定义数据
+	DeviceInfo    string `xml:"device_info,omitempty" json:"device_info,omitempty"`       // 终端设备号(商户自定义，如门店编号)
+	Detail        string `xml:"detail,omitempty" json:"detail,omitempty"`                 // 单品优惠功能字段，需要接入详见单品优惠详细说明
+	FeeType       string `xml:"fee_type,omitempty" json:"fee_type,omitempty"`             // 符合ISO 4217标准的三位字母代码，默认人民币：CNY
+	GoodsTag      string `xml:"goods_tag,omitempty" json:"goods_tag,omitempty"`           // 订单优惠标记，代金券或立减优惠功能的参数
+	LimitPay      string `xml:"limit_pay,omitempty" json:"limit_pay,omitempty"`           // no_credit--指定不能使用信用卡支付
+	TimeStart     string `xml:"time_start,omitempty" json:"time_start,omitempty"`         // 订单生成时间，格式为yyyyMMddHHmmss
+	TimeExpire    string `xml:"time_expire,omitempty" json:"time_expire,omitempty"`       // 订单失效时间，格式为yyyyMMddHHmmss，最短失效时间间隔需大于1分钟
+	Receipt       string `xml:"receipt,omitempty" json:"receipt,omitempty"`               // 电子发票入口开放标识，Y，传入Y时，支付成功消息和支付详情页将出现开票入口
+	ProfitSharing string `xml:"profit_sharing,omitempty" json:"profit_sharing,omitempty"` // 是否需要分账，Y-是，需要分账 N-否，不分账，不传默认不分账
+	SceneInfo     string `xml:"scene_info,omitempty" json:"scene_info,omitempty"`         // 场景信息，该字段用于上报场景信息，目前支持上报实际门店信息。该字段为JSON对象数据，对象格式为{"store_info":{"id": "门店ID","name": "名称","area_code": "编码","address": "地址" }}
+}
+
+func (t TradeMicropay) ReturnType() string {
+	return "xml"
+}
+
+func (t TradeMicropay) ApiPath() string {
+	return "/pay/micropay"
+}
+
+func (t TradeMicropay) Validate() error {
+	var v validator
+	t.SubMerchant.validate(&v)
+	if v.required("body", t.Body) {
+		v.maxLen("body", t.Body, 128)
+	}
+	v.outTradeNo(t.OutTradeNo)
+	v.amount("total_fee", t.TotalFee)
+	if v.required("spbill_create_ip", t.SpbillCreateIp) {
+		v.ip("spbill_create_ip", t.SpbillCreateIp)
+	}
+	if v.required("auth_code", t.AuthCode) {
+		v.authCode(t.AuthCode)
+	}
+	v.maxLen("attach", t.Attach, 127)
+	return v.err()
+}
+
+// TradeMicropayRsp 付款码支付响应参数，result_code 为 FAIL 时 err_code 为 USERPAYING、SYSTEMERROR、BANKERROR 表示支付结果未知，需查询订单确认
+type TradeMicropayRsp struct {
+	PayError
+	SubMerchantRsp
+	AppID              string `xml:"appid" json:"appid"`                                         // 调用接口提交的公众账号ID
+	MchID              string `xml:"mch_id" json:"mch_id"`                                       // 调用接口提交的商户号
+	DeviceInfo         string `xml:"device_info,omitempty" json:"device_info"`                   // 调用接口提交的终端设备号
+	NonceStr           string `xml:"nonce_str" json:"nonce_str"`                                 // 微信返回的随机字符串
+	Sign               string `xml:"sign" json:"sign"`                                           // 微信返回的签名
+	OpenId             string `xml:"openid" json:"openid"`                                       // 用户在商户appid 下的唯一标识
+	SubOpenId          string `xml:"sub_openid,omitempty" json:"sub_openid,omitempty"`           // 用户在子商户appid下的唯一标识，服务商模式下返回
+	IsSubscribe        string `xml:"is_subscribe" json:"is_subscribe"`                           // 已废弃，默认统一返回N
+	TradeType          string `xml:"trade_type" json:"trade_type"`                               // 支付类型为MICROPAY(即扫码支付)
+	BankType           string `xml:"bank_type" json:"bank_type"`                                 // 银行类型，采用字符串类型的银行标识
+	FeeType            string `xml:"fee_type" json:"fee_type"`                                   // 符合ISO 4217标准的三位字母代码，默认人民币：CNY
+	TotalFee           Fen    `xml:"total_fee" json:"total_fee"`                                 // 订单总金额，单位为分，只能为整数
+	SettlementTotalFee Fen    `xml:"settlement_total_fee,omitempty" json:"settlement_total_fee"` // 当订单使用了免充值型优惠券后返回该参数，应结订单金额=订单金额-免充值优惠券金额。
+	CouponFee          Fen    `xml:"coupon_fee,omitempty" json:"coupon_fee"`                     // “代金券”金额<=订单金额，订单金额-“代金券”金额=现金支付金额
+	CashFeeType        string `xml:"cash_fee_type,omitempty" json:"cash_fee_type"`               // 符合ISO 4217标准的三位字母代码，默认人民币：CNY
+	CashFee            Fen    `xml:"cash_fee" json:"cash_fee"`                                   // 订单现金支付金额
+	TransactionId      string `xml:"transaction_id" json:"transaction_id"`                       // 微信支付订单号
+	OutTradeNo         string `xml:"out_trade_no" json:"out_trade_no"`                           // 商户系统内部订单号
+	Attach             string `xml:"attach,omitempty" json:"attach"`                             // 商家数据包，原样返回
+	TimeEnd            string `xml:"time_end" json:"time_end"`                                   // 订单生成时间，格式为yyyyMMddHHmmss
+}
+
+// 转换为查询订单响应参数，付款码支付直接成功时与查询结果保持一致
+func (t TradeMicropayRsp) orderQueryRsp() *TradeOrderQueryRsp {
+	return &TradeOrderQueryRsp{
+		PayError:           t.PayError,
+		SubMerchantRsp:     t.SubMerchantRsp,
+		DeviceInfo:         t.DeviceInfo,
+		OpenId:             t.OpenId,
+		SubOpenId:          t.SubOpenId,
+		IsSubscribe:        t.IsSubscribe,
+		TradeType:          t.TradeType,
+		TradeState:         TradeStateSuccess,
+		BankType:           t.BankType,
+		TotalFee:           t.TotalFee,
+		SettlementTotalFee: t.SettlementTotalFee,
+		FeeType:            t.FeeType,
+		CashFee:            t.CashFee,
+		CashFeeType:        t.CashFeeType,
+		CouponFee:          t.CouponFee,
+		TransactionId:      t.TransactionId,
+		OutTradeNo:         t.OutTradeNo,
+		Attach:             t.Attach,
+		TimeEnd:            t.TimeEnd,
+	}
+}
+
+/* 撤销订单 */
+
+// TradeReverse 撤销订单 https://pay.weixin.qq.com/wiki/doc/api/micropay.php?chapter=9_11&index=3
+type TradeReverse struct {
+	AuxParam
+	SubMerchant
+	OutTradeNo    string `xml:"out_trade_no,omitempty" json:"out_trade_no,omitempty"`     // 商户系统内部订单号，transaction_id、out_trade_no二选一，如果同时存在优先级：transaction_id> out_trade_no
+	TransactionId string `xml:"transaction_id,omitempty" json:"transaction_id,omitempty"` // 微信的订单号，优先使用
+	untilDone     bool   // 由 TradeReverseUntilDone 发起，重试由其负责
+}
+
+func (t TradeReverse) NeedTlsCert() bool {
+	return true
+}
+
+func (t TradeReverse) ReturnType() string {
+	return "xml"
+}
+
+func (t TradeReverse) ApiPath() string {
+	return "/secapi/pay/reverse"
+}
+
+// 撤销订单返回系统错误时需要使用相同的请求内容重试，TradeReverseUntilDone 中不按重试策略重试，避免与其重试叠加
+func (t TradeReverse) Idempotent() bool {
+	return !t.untilDone
+}
+
+func (t TradeReverse) Validate() error {
+	var v validator
+	t.SubMerchant.validate(&v)
+	if t.TransactionId == "" && v.required("out_trade_no", t.OutTradeNo) {
+		v.outTradeNo(t.OutTradeNo)
+	}
+	return v.err()
+}
+
+// TradeReverseRsp 撤销订单响应参数
+type TradeReverseRsp struct {
+	PayError
+	SubMerchantRsp
+	AppID    string `xml:"appid" json:"appid"`         // 微信分配的公众账号ID
+	MchID    string `xml:"mch_id" json:"mch_id"`       // 微信支付分配的商户号
+	NonceStr string `xml:"nonce_str" json:"nonce_str"` // 随机字符串，不长于32位
+	Sign     string `xml:"sign" json:"sign"`           // 签名
+	Recall   string `xml:"recall" json:"recall"`       // 是否需要继续调用撤销，Y-需要，N-不需要
+}
+
+// NeedRecall 撤销未完成，需要再次调用撤销订单接口
+func (t TradeReverseRsp) NeedRecall() bool {
+	return t.Recall == "Y"
 }
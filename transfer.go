@@ -0,0 +1,18 @@
+package wxpay
+
+import "context"
+
+// TransferToBalance 企业付款到零钱 https://pay.weixin.qq.com/wiki/doc/api/tools/mch_pay.php?chapter=14_2
+// POST https://api.mch.weixin.qq.com/mmpaymkttransfers/promotion/transfers
+// 需要加载证书，返回 SYSTEMERROR 等结果未知的错误时，需使用原商户订单号重试，避免重复付款
+func (c *Client) TransferToBalance(ctx context.Context, param TransferToBalance) (result *TransferToBalanceRsp, err error) {
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
+
+// GetTransferInfo 查询企业付款到零钱 https://pay.weixin.qq.com/wiki/doc/api/tools/mch_pay.php?chapter=14_3
+// POST https://api.mch.weixin.qq.com/mmpaymkttransfers/gettransferinfo
+func (c *Client) GetTransferInfo(ctx context.Context, param GetTransferInfo) (result *GetTransferInfoRsp, err error) {
+	err = c.doRequest(ctx, "POST", param, &result)
+	return
+}
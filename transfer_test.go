@@ -0,0 +1,115 @@
+package wxpay
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// 模拟企业付款接口，要求客户端证书，校验 mch_appid、mchid 字段与不带 sign_type 的MD5签名
+func newTransferServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	signer, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fail := func(w http.ResponseWriter, msg string) {
+		b, _ := xml.Marshal(payXml{"return_code": "FAIL", "return_msg": msg})
+		w.Write(b)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mmpaymkttransfers/promotion/transfers", func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			fail(w, "cert required")
+			return
+		}
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			fail(w, err.Error())
+			return
+		}
+		if m["mch_appid"] != testAppId || m["mchid"] != testMchId || m[kFieldAppId] != "" || m[kFieldMchId] != "" || m[kFieldSignType] != "" {
+			fail(w, "invalid fields")
+			return
+		}
+		b, _ := xml.Marshal(payXml{
+			"return_code":      "SUCCESS",
+			"result_code":      "SUCCESS",
+			"mch_appid":        m["mch_appid"],
+			"mchid":            m["mchid"],
+			"nonce_str":        signer.createNonceStr(),
+			"partner_trade_no": m["partner_trade_no"],
+			"payment_no":       "1000018301201505190181489473",
+			"payment_time":     "2015-05-19 15:26:59",
+		})
+		w.Write(b)
+	})
+	mux.HandleFunc("/mmpaymkttransfers/gettransferinfo", func(w http.ResponseWriter, r *http.Request) {
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			fail(w, err.Error())
+			return
+		}
+		if m[kFieldAppId] != testAppId || m[kFieldMchId] != testMchId || m[kFieldSignType] != "" {
+			fail(w, "invalid fields")
+			return
+		}
+		b, _ := xml.Marshal(payXml{
+			"return_code":      "SUCCESS",
+			"result_code":      "SUCCESS",
+			"partner_trade_no": m["partner_trade_no"],
+			"status":           TransferStatusSuccess,
+			"payment_amount":   "100",
+		})
+		w.Write(b)
+	})
+	server := httptest.NewUnstartedServer(mux)
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_TransferToBalance(t *testing.T) {
+	server := newTransferServer(t)
+	pemCert, keyCert := newTestCert(t)
+	// 客户端使用 HMAC-SHA256 时企业付款仍使用MD5签名
+	c := newTestClient(t, server, WithTlsCert(pemCert, keyCert), WithSignType(SignTypeHMACSHA256))
+	var p TransferToBalance
+	p.PartnerTradeNo = "10000098201411111234567890"
+	p.OpenId = "oxTWIuGaIt6gTKsQRLau2M0yL16E"
+	p.CheckName = CheckNameNoCheck
+	p.Amount = 100
+	p.Desc = "理赔"
+	r, err := c.TransferToBalance(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.PaymentNo == "" || r.PartnerTradeNo != p.PartnerTradeNo || r.MchAppId != testAppId {
+		t.Fatalf("unexpected result %+v", r)
+	}
+
+	info, err := c.GetTransferInfo(context.Background(), GetTransferInfo{PartnerTradeNo: p.PartnerTradeNo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Status != TransferStatusSuccess || info.PaymentAmount != 100 {
+		t.Fatalf("unexpected transfer info %+v", info)
+	}
+}
+
+func TestTransferToBalance_Validate(t *testing.T) {
+	var p TransferToBalance
+	p.PartnerTradeNo = "10000098-2014"
+	p.OpenId = "openid"
+	p.CheckName = CheckNameForceCheck
+	p.Amount = 100
+	p.Desc = "理赔"
+	fields := invalidFields(t, p.Validate())
+	if len(fields) != 2 || fields[0] != "partner_trade_no" || fields[1] != "re_user_name" {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+}
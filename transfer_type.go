@@ -0,0 +1,143 @@
+package wxpay
+
+// 校验用户姓名选项
+const (
+	CheckNameNoCheck    = "NO_CHECK"    // 不校验真实姓名
+	CheckNameForceCheck = "FORCE_CHECK" // 强校验真实姓名
+)
+
+// 企业付款状态
+const (
+	TransferStatusSuccess    = "SUCCESS"    // 转账成功
+	TransferStatusFailed     = "FAILED"     // 转账失败
+	TransferStatusProcessing = "PROCESSING" // 处理中
+)
+
+/* 企业付款到零钱 */
+
+// TransferToBalance 企业付款到零钱 https://pay.weixin.qq.com/wiki/doc/api/tools/mch_pay.php?chapter=14_2
+type TransferToBalance struct {
+	AuxParam
+	PartnerTradeNo string `xml:"partner_trade_no" json:"partner_trade_no"`                     // 商户订单号，需保持唯一性(只能是字母或者数字，不能包含有其它字符)
+	OpenId         string `xml:"openid" json:"openid"`                                         // 商户appid下，某用户的openid
+	CheckName      string `xml:"check_name" json:"check_name"`                                 // NO_CHECK：不校验真实姓名 FORCE_CHECK：强校验真实姓名
+	ReUserName     string `xml:"re_user_name,omitempty" json:"re_user_name,omitempty"`         // 收款用户真实姓名。如果check_name设置为FORCE_CHECK，则必填用户真实姓名
+	Amount         Fen    `xml:"amount" json:"amount"`                                         // 企业付款金额，单位为分
+	Desc           string `xml:"desc" json:"desc"`                                             // 企业付款备注，必填。注意：备注中的敏感词会被转成字符*
+	SpbillCreateIp string `xml:"spbill_create_ip,omitempty" json:"spbill_create_ip,omitempty"` // 该IP同在商户平台设置的IP白名单中的IP没有关联，该IP可传用户端或者服务端的IP。
+	DeviceInfo     string `xml:"device_info,omitempty" json:"device_info,omitempty"`           // 微信支付分配的终端设备号
+}
+
+func (t TransferToBalance) NeedVerify() bool {
+	return false
+}
+
+func (t TransferToBalance) NeedTlsCert() bool {
+	return true
+}
+
+func (t TransferToBalance) ReturnType() string {
+	return "xml"
+}
+
+func (t TransferToBalance) ApiPath() string {
+	return "/mmpaymkttransfers/promotion/transfers"
+}
+
+// 企业付款只支持MD5签名，且不传签名类型
+func (t TransferToBalance) SignType() string {
+	return SignTypeMD5
+}
+
+func (t TransferToBalance) Fields() ParamFields {
+	return ParamFields{AppId: "mch_appid", MchId: "mchid"}
+}
+
+func (t TransferToBalance) Validate() error {
+	var v validator
+	v.partnerTradeNo(t.PartnerTradeNo)
+	v.required("openid", t.OpenId)
+	switch t.CheckName {
+	case CheckNameNoCheck:
+	case CheckNameForceCheck:
+		v.required("re_user_name", t.ReUserName)
+	default:
+		v.add("check_name", "must be NO_CHECK or FORCE_CHECK")
+	}
+	v.amount("amount", t.Amount)
+	if v.required("desc", t.Desc) {
+		v.maxLen("desc", t.Desc, 100)
+	}
+	if t.SpbillCreateIp != "" {
+		v.ip("spbill_create_ip", t.SpbillCreateIp)
+	}
+	return v.err()
+}
+
+// TransferToBalanceRsp 企业付款到零钱响应参数，result_code 为 FAIL 且 err_code 为 SYSTEMERROR 时结果未知，需使用原商户订单号重试或查询
+type TransferToBalanceRsp struct {
+	PayError
+	MchAppId       string `xml:"mch_appid" json:"mch_appid"`               // 申请商户号的appid或商户号绑定的appid
+	MchId          string `xml:"mchid" json:"mchid"`                       // 微信支付分配的商户号
+	DeviceInfo     string `xml:"device_info" json:"device_info"`           // 微信支付分配的终端设备号
+	NonceStr       string `xml:"nonce_str" json:"nonce_str"`               // 随机字符串，不长于32位
+	PartnerTradeNo string `xml:"partner_trade_no" json:"partner_trade_no"` // 商户订单号，需保持历史全局唯一性
+	PaymentNo      string `xml:"payment_no" json:"payment_no"`             // 企业付款成功，返回的微信付款单号
+	PaymentTime    string `xml:"payment_time" json:"payment_time"`         // 企业付款成功时间，如 2015-05-19 15:26:59
+}
+
+/* 查询企业付款到零钱 */
+
+// GetTransferInfo 查询企业付款 https://pay.weixin.qq.com/wiki/doc/api/tools/mch_pay.php?chapter=14_3
+type GetTransferInfo struct {
+	AuxParam
+	PartnerTradeNo string `xml:"partner_trade_no" json:"partner_trade_no"` // 商户调用企业付款API时使用的商户订单号
+}
+
+func (t GetTransferInfo) NeedVerify() bool {
+	return false
+}
+
+func (t GetTransferInfo) NeedTlsCert() bool {
+	return true
+}
+
+func (t GetTransferInfo) ReturnType() string {
+	return "xml"
+}
+
+func (t GetTransferInfo) ApiPath() string {
+	return "/mmpaymkttransfers/gettransferinfo"
+}
+
+// 查询企业付款只支持MD5签名，且不传签名类型
+func (t GetTransferInfo) SignType() string {
+	return SignTypeMD5
+}
+
+func (t GetTransferInfo) Fields() ParamFields {
+	return ParamFields{AppId: kFieldAppId, MchId: kFieldMchId}
+}
+
+func (t GetTransferInfo) Validate() error {
+	var v validator
+	v.partnerTradeNo(t.PartnerTradeNo)
+	return v.err()
+}
+
+// GetTransferInfoRsp 查询企业付款响应参数
+type GetTransferInfoRsp struct {
+	PayError
+	PartnerTradeNo string `xml:"partner_trade_no" json:"partner_trade_no"` // 商户使用查询API填写的单号的原路返回
+	AppId          string `xml:"appid" json:"appid"`                       // 商户号的appid
+	MchId          string `xml:"mch_id" json:"mch_id"`                     // 微信支付分配的商户号
+	DetailId       string `xml:"detail_id" json:"detail_id"`               // 调用企业付款API时，微信系统内部产生的单号
+	Status         string `xml:"status" json:"status"`                     // 转账状态，SUCCESS:转账成功 FAILED:转账失败 PROCESSING:处理中
+	Reason         string `xml:"reason" json:"reason"`                     // 失败原因，如果失败则有失败原因
+	OpenId         string `xml:"openid" json:"openid"`                     // 转账的openid
+	TransferName   string `xml:"transfer_name" json:"transfer_name"`       // 收款用户姓名
+	PaymentAmount  Fen    `xml:"payment_amount" json:"payment_amount"`     // 付款金额单位为分
+	TransferTime   string `xml:"transfer_time" json:"transfer_time"`       // 发起转账的时间
+	PaymentTime    string `xml:"payment_time" json:"payment_time"`         // 企业付款成功时间
+	Desc           string `xml:"desc" json:"desc"`                         // 企业付款备注
+}
@@ -0,0 +1,177 @@
+package wxpay
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldError 参数校验错误
+type FieldError struct {
+	Field  string // 参数名，如：out_trade_no
+	Reason string // 错误原因
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Reason)
+}
+
+// ValidationErrors 请求参数校验错误，请求微信接口前校验，包含所有不合法的参数
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return "wxpay: invalid params, " + strings.Join(msgs, "; ")
+}
+
+var (
+	outTradeNoRegexp  = regexp.MustCompile(`^[0-9A-Za-z_\-|*]{6,32}$`)
+	outRefundNoRegexp = regexp.MustCompile(`^[0-9A-Za-z_\-|*@]{1,64}$`)
+	authCodeRegexp    = regexp.MustCompile(`^1[0-5][0-9]{16}$`)
+	partnerNoRegexp   = regexp.MustCompile(`^[0-9A-Za-z]{1,32}$`)
+	bankPartnerRegexp = regexp.MustCompile(`^[0-9A-Za-z]{8,32}$`)
+)
+
+const (
+	kTradeTimeFormat   = "20060102150405" // 订单时间格式 yyyyMMddHHmmss
+	kBillDateFormat    = "20060102"       // 账单日期格式 yyyyMMdd
+	kTradeMinExpireGap = 5 * time.Minute  // 订单失效时间与生成时间的最短间隔
+)
+
+// 参数校验器，收集所有错误后统一返回
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) add(field, reason string) {
+	v.errs = append(v.errs, FieldError{Field: field, Reason: reason})
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// 必填
+func (v *validator) required(field, value string) bool {
+	if value == "" {
+		v.add(field, "is required")
+		return false
+	}
+	return true
+}
+
+// 最大字符数
+func (v *validator) maxLen(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.add(field, fmt.Sprintf("must be at most %d characters", max))
+	}
+}
+
+// 商户订单号，6-32个字符，只能是数字、大小写字母_-|*
+func (v *validator) outTradeNo(value string) {
+	if !outTradeNoRegexp.MatchString(value) {
+		v.add("out_trade_no", "must be 6-32 characters of [0-9A-Za-z_-|*]")
+	}
+}
+
+// 商户退款单号，64个字符内，只能是数字、大小写字母_-|*@
+func (v *validator) outRefundNo(value string) {
+	if !outRefundNoRegexp.MatchString(value) {
+		v.add("out_refund_no", "must be 1-64 characters of [0-9A-Za-z_-|*@]")
+	}
+}
+
+// 分账单号、回退单号，64个字符内，只能是数字、大小写字母_-|*@
+func (v *validator) outNo(field, value string) {
+	if !outRefundNoRegexp.MatchString(value) {
+		v.add(field, "must be 1-64 characters of [0-9A-Za-z_-|*@]")
+	}
+}
+
+// 企业付款商户订单号，32个字符内，只能是字母或者数字
+func (v *validator) partnerTradeNo(value string) {
+	if !partnerNoRegexp.MatchString(value) {
+		v.add("partner_trade_no", "must be 1-32 characters of [0-9A-Za-z]")
+	}
+}
+
+// 企业付款到银行卡商户订单号，8-32个字符，只能是字母或者数字
+func (v *validator) bankPartnerTradeNo(value string) {
+	if !bankPartnerRegexp.MatchString(value) {
+		v.add("partner_trade_no", "must be 8-32 characters of [0-9A-Za-z]")
+	}
+}
+
+// 红包商户订单号，28个字符内，只能是字母或者数字
+func (v *validator) mchBillNo(value string) {
+	if !partnerNoRegexp.MatchString(value) || len(value) > 28 {
+		v.add("mch_billno", "must be 1-28 characters of [0-9A-Za-z]")
+	}
+}
+
+// 付款码，18位纯数字，以10、11、12、13、14、15开头
+func (v *validator) authCode(value string) {
+	if !authCodeRegexp.MatchString(value) {
+		v.add("auth_code", "must be 18 digits starting with 10-15")
+	}
+}
+
+// 金额
+func (v *validator) amount(field string, value Fen) {
+	if err := value.Validate(); err != nil {
+		v.add(field, fmt.Sprintf("must be between 1 and %d fen", MaxFen))
+	}
+}
+
+// 订单时间，格式为yyyyMMddHHmmss
+func (v *validator) tradeTime(field, value string) (time.Time, bool) {
+	t, err := time.Parse(kTradeTimeFormat, value)
+	if err != nil {
+		v.add(field, "must be formatted as yyyyMMddHHmmss")
+		return t, false
+	}
+	return t, true
+}
+
+// 账单日期，格式为yyyyMMdd
+func (v *validator) billDate(value string) {
+	if _, err := time.Parse(kBillDateFormat, value); err != nil {
+		v.add("bill_date", "must be formatted as yyyyMMdd")
+	}
+}
+
+// 回调地址，必须为直接可访问的url，不能携带参数
+func (v *validator) notifyUrl(value string) {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add("notify_url", "must be an absolute http(s) url")
+		return
+	}
+	if u.RawQuery != "" {
+		v.add("notify_url", "must not contain query parameters")
+	}
+}
+
+// IP地址，支持IPV4和IPV6
+func (v *validator) ip(field, value string) {
+	if net.ParseIP(value) == nil {
+		v.add(field, "must be a valid IPv4 or IPv6 address")
+	}
+}
+
+// 二维码宽度，不传时使用默认值430
+func (v *validator) qrcodeWidth(value int) {
+	if value != 0 && (value < 280 || value > 1280) {
+		v.add("width", "must be between 280 and 1280")
+	}
+}
@@ -0,0 +1,120 @@
+package wxpay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func validTradeApplet() TradeApplet {
+	var p TradeApplet
+	p.Body = "支付测试"
+	p.OutTradeNo = "TEST2023112717521212345678"
+	p.TotalFee = 1
+	p.SpbillCreateIp = "127.0.0.1"
+	p.NotifyUrl = "https://www.weixin.qq.com/wxpay/pay.php"
+	p.OpenId = "openid"
+	return p
+}
+
+// 校验失败的字段名
+func invalidFields(t *testing.T, err error) []string {
+	t.Helper()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	fields := make([]string, 0, len(verrs))
+	for _, fe := range verrs {
+		fields = append(fields, fe.Field)
+	}
+	return fields
+}
+
+func TestTrade_Validate(t *testing.T) {
+	if err := validTradeApplet().Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		fn    func(p *TradeApplet)
+		field string
+	}{
+		{"out_trade_no too short", func(p *TradeApplet) { p.OutTradeNo = "12345" }, "out_trade_no"},
+		{"out_trade_no invalid char", func(p *TradeApplet) { p.OutTradeNo = "TEST#20231127" }, "out_trade_no"},
+		{"body missing", func(p *TradeApplet) { p.Body = "" }, "body"},
+		{"total_fee zero", func(p *TradeApplet) { p.TotalFee = 0 }, "total_fee"},
+		{"ip invalid", func(p *TradeApplet) { p.SpbillCreateIp = "localhost" }, "spbill_create_ip"},
+		{"notify_url query", func(p *TradeApplet) { p.NotifyUrl = "https://example.com/notify?a=1" }, "notify_url"},
+		{"openid missing", func(p *TradeApplet) { p.OpenId = "" }, "openid"},
+		{"time_start format", func(p *TradeApplet) { p.TimeStart = "2023-11-27 17:52:12" }, "time_start"},
+		{"time_expire too early", func(p *TradeApplet) {
+			p.TimeStart = "20231127175212"
+			p.TimeExpire = "20231127175612"
+		}, "time_expire"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := validTradeApplet()
+			tt.fn(&p)
+			fields := invalidFields(t, p.Validate())
+			if len(fields) != 1 || fields[0] != tt.field {
+				t.Fatalf("expected %s, got %v", tt.field, fields)
+			}
+		})
+	}
+}
+
+func TestTrade_ValidateTradeType(t *testing.T) {
+	var native TradeNative
+	native.Trade = validTradeApplet().Trade
+	if fields := invalidFields(t, native.Validate()); len(fields) != 1 || fields[0] != "product_id" {
+		t.Fatalf("expected product_id, got %v", fields)
+	}
+	var wap TradeWap
+	wap.Trade = validTradeApplet().Trade
+	if fields := invalidFields(t, wap.Validate()); len(fields) != 1 || fields[0] != "scene_info" {
+		t.Fatalf("expected scene_info, got %v", fields)
+	}
+	var refund TradeRefund
+	refund.TransactionId = "4200000000000000000000000000"
+	refund.OutRefundNo = "R1"
+	refund.TotalFee = 1
+	refund.RefundFee = 2
+	if fields := invalidFields(t, refund.Validate()); len(fields) != 1 || fields[0] != "refund_fee" {
+		t.Fatalf("expected refund_fee, got %v", fields)
+	}
+	var qrcode GetWxACodeUnLimit
+	qrcode.Scene = "a=1"
+	qrcode.Width = 100
+	if fields := invalidFields(t, qrcode.Validate()); len(fields) != 1 || fields[0] != "width" {
+		t.Fatalf("expected width, got %v", fields)
+	}
+}
+
+func TestClient_ValidateBeforeRequest(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+	c, err := New(testAppId, testSecret, WithApiHost(server.URL), WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := validTradeApplet()
+	p.OutTradeNo = ""
+	if _, err = c.TradeApplet(context.Background(), p); err == nil {
+		t.Fatal("expected validation error")
+	}
+	invalidFields(t, err)
+	if _, err = c.Code2Session(context.Background(), Code2Session{}); err == nil {
+		t.Fatal("expected validation error")
+	}
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Fatalf("expected no request, got %d", n)
+	}
+}
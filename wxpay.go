@@ -2,7 +2,10 @@ package wxpay
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
 	"crypto/md5"
+	"crypto/sha256"
 	"crypto/tls"
 	"encoding/hex"
 	"encoding/json"
@@ -23,52 +26,84 @@ var (
 	ErrWxNullParams         = errors.New("wxpay: param is null")
 	ErrWxReturnCodeNotFound = errors.New("wxpay: return_code not found")
 	ErrWxPemKeyNotFound     = errors.New("wxpay: wxpay pem or key cert not found")
+	ErrWxHttpStatus         = errors.New("wxpay: unexpected http status")
 )
 
+// Client 微信接口客户端，通过 New 创建后不可修改，可在多个 goroutine 中并发使用
 type Client struct {
 	appId          string
 	secret         string
 	mchId          string
 	mchSecret      string
 	host           string
-	signType       string
+	apiDomain      string
+	payDomain      string
+	signer         Signer
 	pemCert        []byte
 	keyCert        []byte
 	location       *time.Location
 	client         *http.Client
+	tlsClient      *http.Client
 	onReceivedData func(method string, data []byte)
+	tokenProvider  AccessTokenProvider
+	tokenStore     TokenStore
+	sharedToken    AccessTokenProvider // 未设置凭据管理与凭据存储时使用，Registry 中同一appid的商户共享
+	micropayWait   time.Duration
+	micropayPoll   time.Duration
+	rsaKey         *rsaKeyCache
+	retry          RetryPolicy
+	err            error
 }
 
 type OptionFunc func(c *Client)
 
-// 设置请求链接
+// 设置请求链接，设置后所有接口均请求该域名（或代理地址）加上接口路径，代理地址可带路径前缀，
+// 兼容旧用法：传入以接口路径结尾的完整接口链接时，该接口直接请求该链接
 func WithApiHost(host string) OptionFunc {
 	return func(c *Client) {
 		if host != "" {
-			c.host = host
+			c.host = strings.TrimRight(host, "/")
 		}
 	}
 }
 
-// 设置小程序登录链接
-func WithJsCodeHost() OptionFunc {
+// 设置小程序接口域名，默认 https://api.weixin.qq.com
+func WithApiDomain(domain string) OptionFunc {
 	return func(c *Client) {
-		c.host = "https://api.weixin.qq.com/sns/jscode2session"
+		if domain != "" {
+			c.apiDomain = strings.TrimRight(domain, "/")
+		}
 	}
 }
 
-// 设置支付请求链接
-func WithPayHost() OptionFunc {
+// 设置支付接口域名，默认 https://api.mch.weixin.qq.com，可切换为备用域名 https://api2.mch.weixin.qq.com
+func WithPayDomain(domain string) OptionFunc {
 	return func(c *Client) {
-		c.host = "https://api.mch.weixin.qq.com/pay/unifiedorder"
+		if domain != "" {
+			c.payDomain = strings.TrimRight(domain, "/")
+		}
 	}
 }
 
+// 设置小程序登录链接
+//
+// Deprecated: 请求链接已由各接口参数的 ApiPath 决定，无需再设置，本方法仅恢复默认小程序接口域名
+func WithJsCodeHost() OptionFunc {
+	return WithApiDomain(kApiDomain)
+}
+
+// 设置支付请求链接
+//
+// Deprecated: 请求链接已由各接口参数的 ApiPath 决定，无需再设置，本方法仅恢复默认支付接口域名
+func WithPayHost() OptionFunc {
+	return WithPayDomain(kPayDomain)
+}
+
 // 设置申请退款请求链接
+//
+// Deprecated: 请求链接已由各接口参数的 ApiPath 决定，无需再设置，本方法仅恢复默认支付接口域名
 func WithRefundHost() OptionFunc {
-	return func(c *Client) {
-		c.host = "https://api.mch.weixin.qq.com/secapi/pay/refund"
-	}
+	return WithPayDomain(kPayDomain)
 }
 
 // 设置商户号信息
@@ -80,7 +115,102 @@ func WithMchInformation(id, secret string) OptionFunc {
 		if secret != "" {
 			c.mchSecret = secret
 		}
-		c.signType = "MD5"
+	}
+}
+
+// 设置签名类型，支持 MD5 与 HMAC-SHA256，默认 MD5
+func WithSignType(signType string) OptionFunc {
+	return func(c *Client) {
+		signer, ok := signers[signType]
+		if !ok {
+			c.err = fmt.Errorf("wxpay: unsupported sign type %s", signType)
+			return
+		}
+		c.signer = signer
+	}
+}
+
+// 设置自定义签名算法
+func WithSigner(signer Signer) OptionFunc {
+	return func(c *Client) {
+		if signer != nil {
+			c.signer = signer
+		}
+	}
+}
+
+// 设置请求使用的 http.Client，默认 http.DefaultClient，证书请求会复用其 Transport 配置
+func WithHttpClient(client *http.Client) OptionFunc {
+	return func(c *Client) {
+		if client != nil {
+			c.client = client
+		}
+	}
+}
+
+// 设置证书内容，申请退款等接口需要
+func WithTlsCert(pemCert, keyCert []byte) OptionFunc {
+	return func(c *Client) {
+		c.pemCert = pemCert
+		c.keyCert = keyCert
+	}
+}
+
+// 设置证书文件路径，申请退款等接口需要
+func WithTlsCertFile(pemCertPath, keyCertPath string) OptionFunc {
+	return func(c *Client) {
+		p, err := os.ReadFile(pemCertPath)
+		if err != nil {
+			c.err = fmt.Errorf("wxpay: read pem cert fail, err = %s", err.Error())
+			return
+		}
+		k, err := os.ReadFile(keyCertPath)
+		if err != nil {
+			c.err = fmt.Errorf("wxpay: read key cert fail, %s", err.Error())
+			return
+		}
+		c.pemCert = p
+		c.keyCert = k
+	}
+}
+
+// 设置接口返回内容回调，可用于记录日志
+func WithReceivedData(fn func(method string, data []byte)) OptionFunc {
+	return func(c *Client) {
+		c.onReceivedData = fn
+	}
+}
+
+// 设置接口调用凭据管理，默认使用 NewAccessTokenProvider 在内存中缓存凭据
+func WithAccessTokenProvider(provider AccessTokenProvider) OptionFunc {
+	return func(c *Client) {
+		if provider != nil {
+			c.tokenProvider = provider
+		}
+	}
+}
+
+// 设置企业付款到银行卡使用的RSA公钥（PKCS#1格式），设置后不再请求获取RSA公钥接口
+func WithRSAPublicKey(pemKey []byte) OptionFunc {
+	return func(c *Client) {
+		key, err := parsePKCS1PublicKey(pemKey)
+		if err != nil {
+			c.err = err
+			return
+		}
+		c.rsaKey.key = key
+	}
+}
+
+// 设置付款码支付等待用户支付的最长时间与查询订单的间隔，默认30秒与5秒，超时未支付时撤销订单
+func WithMicropayPolling(wait, interval time.Duration) OptionFunc {
+	return func(c *Client) {
+		if wait > 0 {
+			c.micropayWait = wait
+		}
+		if interval > 0 {
+			c.micropayPoll = interval
+		}
 	}
 }
 
@@ -92,35 +222,35 @@ func New(appId, secret string, opts ...OptionFunc) (nClient *Client, err error)
 	nClient = &Client{}
 	nClient.appId = appId
 	nClient.secret = secret
+	nClient.apiDomain = kApiDomain
+	nClient.payDomain = kPayDomain
 	nClient.client = http.DefaultClient
+	nClient.signer = signers[SignTypeMD5]
 	nClient.location = time.Local
-	nClient.LoadOptionFunc(opts...)
-	return
-}
-
-// 加载接口链接
-func (c *Client) LoadOptionFunc(opts ...OptionFunc) {
+	nClient.micropayWait = kMicropayWait
+	nClient.micropayPoll = kMicropayPollInterval
+	nClient.rsaKey = &rsaKeyCache{}
 	for _, opt := range opts {
 		if opt != nil {
-			opt(c)
+			opt(nClient)
 		}
 	}
-}
-
-// 加载证书文件
-func (c *Client) LoadAppCertPemKeyFromFile(pemCertPath, keyCertPath string) (err error) {
-	p, err := os.ReadFile(pemCertPath)
-	if err != nil {
-		err = fmt.Errorf("wxpay: read pem cert fail, err = %s", err.Error())
-		return
+	if nClient.err != nil {
+		return nil, nClient.err
 	}
-	k, err := os.ReadFile(keyCertPath)
-	if err != nil {
-		err = fmt.Errorf("wxpay: read key cert fail, %s", err.Error())
-		return
+	if nClient.tokenProvider == nil && nClient.tokenStore != nil {
+		nClient.tokenProvider = NewStoreAccessTokenProvider(nClient, nClient.tokenStore)
+	} else if nClient.tokenProvider == nil && nClient.sharedToken != nil {
+		nClient.tokenProvider = nClient.sharedToken
+	} else if nClient.tokenProvider == nil {
+		nClient.tokenProvider = NewAccessTokenProvider(nClient)
+	}
+	// 加载了证书则提前创建证书请求客户端
+	if len(nClient.pemCert) > 0 || len(nClient.keyCert) > 0 {
+		if nClient.tlsClient, err = nClient.newTlsClient(); err != nil {
+			return nil, err
+		}
 	}
-	c.pemCert = p
-	c.keyCert = k
 	return
 }
 
@@ -143,12 +273,31 @@ func (c *Client) LoadTlsCertConfig() (tlsConfig *tls.Config, err error) {
 	return
 }
 
+// 创建证书请求客户端，复用 http.Client 的配置，避免修改共享的 Transport
+func (c *Client) newTlsClient() (*http.Client, error) {
+	tlsConfig, err := c.LoadTlsCertConfig()
+	if err != nil {
+		return nil, err
+	}
+	var transport *http.Transport
+	if t, ok := c.client.Transport.(*http.Transport); ok {
+		transport = t.Clone()
+	} else {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	transport.TLSClientConfig = tlsConfig
+	tlsClient := *c.client
+	tlsClient.Transport = transport
+	return &tlsClient, nil
+}
+
 // 请求参数
 func (c *Client) URLValues(param Param) (value url.Values, err error) {
 	var values = url.Values{}
+	var fields = param.Fields()
 	// 是否需要APPID
 	if param.NeedAppId() {
-		values.Add(kFieldAppId, c.appId)
+		values.Add(fields.AppId, c.appId)
 	}
 	// 是否需要密钥
 	if param.NeedSecret() {
@@ -163,49 +312,116 @@ func (c *Client) URLValues(param Param) (value url.Values, err error) {
 	}
 	// 判断是否需要签名
 	if param.NeedSign() {
-		values.Add(kFieldMchId, c.mchId)
+		values.Add(fields.MchId, c.mchId)
 		values.Add(kFieldNonceStr, c.createNonceStr())
-		values.Add(kFieldSignType, c.signType)
-		signature := c.sign(values)
+		signer, err := c.paramSigner(param)
+		if err != nil {
+			return nil, err
+		}
+		if fields.SignType != "" {
+			values.Add(fields.SignType, signer.SignType())
+		}
 		// 添加签名
-		values.Add(kFieldSign, signature)
+		values.Add(kFieldSign, signer.Sign(c.formatBizQueryParaMap(values), c.mchSecret))
 	}
 	return values, nil
 }
 
 // 结构体转map
 func (c *Client) structToMap(stu interface{}) map[string]string {
-	// 结构体转map
+	// 结构体转map，金额等数字字段按原样转为字符串
 	m, _ := json.Marshal(&stu)
-	var parameters map[string]string
-	_ = json.Unmarshal(m, &parameters)
+	var raw map[string]json.RawMessage
+	_ = json.Unmarshal(m, &raw)
+	parameters := make(map[string]string, len(raw))
+	for k, v := range raw {
+		var str string
+		if json.Unmarshal(v, &str) == nil {
+			parameters[k] = str
+		} else {
+			parameters[k] = string(v)
+		}
+	}
 	return parameters
 }
 
 // 生成签名
 func (c *Client) sign(parameters url.Values) string {
-	signStr := c.formatBizQueryParaMap(parameters)
-	signStr = fmt.Sprintf("%s&key=%s", signStr, c.mchSecret)
-	h := md5.New()
-	h.Write([]byte(signStr))
-	sign := hex.EncodeToString(h.Sum(nil))
-	return strings.ToUpper(sign)
+	return c.signer.Sign(c.formatBizQueryParaMap(parameters), c.mchSecret)
+}
+
+// 接口使用的签名算法，接口指定了签名类型时使用对应的算法
+func (c *Client) paramSigner(param Param) (Signer, error) {
+	signType := param.SignType()
+	if signType == "" || signType == c.signer.SignType() {
+		return c.signer, nil
+	}
+	signer, ok := signers[signType]
+	if !ok {
+		return nil, fmt.Errorf("wxpay: unsupported sign type %s", signType)
+	}
+	return signer, nil
 }
 
-// 生成小程序签名
-func (c *Client) createAppletPaySign(timestamp, prepayId, nonceStr string) string {
+// 生成小程序签名，服务商模式下 appId 为子商户的 sub_appid
+func (c *Client) createAppletPaySign(appId, timestamp, prepayId, nonceStr string) string {
 	wxPayInfo := make(map[string]string, 5)
-	wxPayInfo["appId"] = c.appId
+	wxPayInfo["appId"] = appId
 	wxPayInfo["timeStamp"] = timestamp
 	wxPayInfo["nonceStr"] = nonceStr
 	wxPayInfo["package"] = fmt.Sprintf("prepay_id=%s", prepayId)
-	wxPayInfo["signType"] = "MD5"
-	signStr := c.formatQueryParaMap(wxPayInfo)
-	signStr = fmt.Sprintf("%s&key=%s", signStr, c.mchSecret)
+	wxPayInfo["signType"] = c.signer.SignType()
+	return c.signer.Sign(c.formatQueryParaMap(wxPayInfo), c.mchSecret)
+}
+
+// 生成小程序红包签名，package 为urlencode后的值，固定使用MD5签名，signType 不参与签名
+func (c *Client) createRedPackPaySign(timestamp, nonceStr, pkg string) string {
+	info := make(map[string]string, 4)
+	info["appId"] = c.appId
+	info["timeStamp"] = timestamp
+	info["nonceStr"] = nonceStr
+	info["package"] = pkg
+	return signers[SignTypeMD5].Sign(c.formatQueryParaMap(info), c.mchSecret)
+}
+
+// Signer 签名算法，可通过 WithSigner 自定义
+type Signer interface {
+	// SignType 签名类型，即请求参数 sign_type 的值
+	SignType() string
+
+	// Sign 对按字典序拼接好的参数字符串签名，返回大写的签名结果，key 为商户支付密钥
+	Sign(content, key string) string
+}
+
+var signers = map[string]Signer{
+	SignTypeMD5:        md5Signer{},
+	SignTypeHMACSHA256: hmacSha256Signer{},
+}
+
+// MD5签名
+type md5Signer struct{}
+
+func (md5Signer) SignType() string {
+	return SignTypeMD5
+}
+
+func (md5Signer) Sign(content, key string) string {
 	h := md5.New()
-	h.Write([]byte(signStr))
-	sign := hex.EncodeToString(h.Sum(nil))
-	return strings.ToUpper(sign)
+	h.Write([]byte(fmt.Sprintf("%s&key=%s", content, key)))
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+}
+
+// HMAC-SHA256签名
+type hmacSha256Signer struct{}
+
+func (hmacSha256Signer) SignType() string {
+	return SignTypeHMACSHA256
+}
+
+func (hmacSha256Signer) Sign(content, key string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(fmt.Sprintf("%s&key=%s", content, key)))
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
 }
 
 // 格式化参数，签名过程需要使用
@@ -274,9 +490,8 @@ func (c *Client) createNonceStr() string {
 	length := 32
 	strByte := []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890")
 	bytes := make([]byte, length)
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	for i := 0; i < length; i++ {
-		bytes[i] = strByte[r.Intn(len(strByte))]
+		bytes[i] = strByte[rand.Intn(len(strByte))]
 	}
 	return string(bytes)
 }
@@ -319,16 +534,143 @@ func (m *payXml) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	return nil
 }
 
+// 请求链接，由域名与接口路径拼接而成
+func (c *Client) requestUrl(param Param) string {
+	apiPath := param.ApiPath()
+	// 接口路径为完整链接时直接使用，如风险验证接口的域名为 fraud.mch.weixin.qq.com
+	if u, err := url.Parse(apiPath); err == nil && u.IsAbs() {
+		if c.host == "" {
+			return apiPath
+		}
+		apiPath = u.Path
+	}
+	if c.host != "" {
+		u, err := url.Parse(c.host)
+		if err != nil {
+			return c.host + apiPath
+		}
+		// 兼容旧用法：传入的是该接口的完整链接（可带 access_token 等参数）时直接使用
+		if apiPath != "" && strings.HasSuffix(strings.TrimRight(u.Path, "/"), strings.TrimRight(apiPath, "/")) {
+			return c.host
+		}
+		// 代理地址可带路径前缀，如 https://gw.example.com/wxpay
+		return u.JoinPath(apiPath).String()
+	}
+	// 需要商户签名的均为支付接口，其余为小程序接口
+	if param.NeedSign() {
+		return c.payDomain + apiPath
+	}
+	return c.apiDomain + apiPath
+}
+
 // 请求主方法
-func (c *Client) doRequest(method string, param Param, result interface{}) (err error) {
+func (c *Client) doRequest(ctx context.Context, method string, param Param, result interface{}) (err error) {
+	if err = param.Validate(); err != nil {
+		return
+	}
+	// 不需要接口调用凭据，或请求链接中已自行带上凭据
+	if !param.NeedAccessToken() || strings.Contains(c.requestUrl(param), kFieldAccessToken+"=") {
+		return c.do(ctx, method, param, "", result)
+	}
+	for attempt := 0; ; attempt++ {
+		var accessToken string
+		if accessToken, err = c.tokenProvider.AccessToken(ctx); err != nil {
+			return
+		}
+		err = c.do(ctx, method, param, accessToken, result)
+		// 凭据过期或失效时刷新凭据后重试一次
+		var aErr *AppletError
+		if attempt == 0 && errors.As(err, &aErr) && aErr.IsAccessTokenInvalid() {
+			if err = c.tokenProvider.Invalidate(ctx, accessToken); err != nil {
+				return
+			}
+			continue
+		}
+		return
+	}
+}
+
+// 发起请求并解析返回数据，可重试的接口按重试策略重试，重试时复用同一请求内容（相同的 nonce_str 与签名）
+func (c *Client) do(ctx context.Context, method string, param Param, accessToken string, result interface{}) (err error) {
+	req, httpClient, err := c.newRequest(ctx, method, param, accessToken)
+	if err != nil {
+		return
+	}
+	for attempt := 1; ; attempt++ {
+		var errCode string
+		errCode, err = c.roundTrip(httpClient, req, method, param, result)
+		if !param.Idempotent() || !c.retry.shouldRetry(ctx, attempt, err, errCode) {
+			return
+		}
+		timer := time.NewTimer(c.retry.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// 发送一次请求并解析返回数据，errCode 为xml接口业务结果失败时返回的 err_code
+func (c *Client) roundTrip(httpClient *http.Client, req *http.Request, method string, param Param, result interface{}) (errCode string, err error) {
+	req = req.Clone(req.Context())
+	if req.GetBody != nil {
+		if req.Body, err = req.GetBody(); err != nil {
+			return
+		}
+	}
+	rsp, err := httpClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer rsp.Body.Close()
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return "", fmt.Errorf("解析返回数据失败: %w", err)
+	}
+	// 网关错误等非2xx响应不是微信接口的返回数据
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return "", fmt.Errorf("%w, %s", ErrWxHttpStatus, rsp.Status)
+	}
+	if param.ReturnType() == "xml" {
+		var pErr PayError
+		if xml.Unmarshal(bodyBytes, &pErr) == nil && pErr.ResultCode != string(ReturnCodeSuccess) {
+			errCode = pErr.ErrCode
+		}
+	}
+	err = c.decode(bodyBytes, method, param.ReturnType(), param.NeedVerify(), param.SignType(), result)
+	return
+}
+
+// 发起请求，返回的 Body 由调用方关闭
+func (c *Client) send(ctx context.Context, method string, param Param, accessToken string) (rsp *http.Response, err error) {
+	req, httpClient, err := c.newRequest(ctx, method, param, accessToken)
+	if err != nil {
+		return
+	}
+	// 发起请求数据
+	return httpClient.Do(req)
+}
+
+// 创建请求，请求内容只签名一次，可通过 GetBody 重复读取，同时返回请求使用的 http.Client
+func (c *Client) newRequest(ctx context.Context, method string, param Param, accessToken string) (req *http.Request, httpClient *http.Client, err error) {
 	// 创建一个请求
-	req, _ := http.NewRequest(method, c.host, nil)
+	req, err = http.NewRequestWithContext(ctx, method, c.requestUrl(param), nil)
+	if err != nil {
+		return
+	}
+	if accessToken != "" {
+		query := req.URL.Query()
+		query.Set(kFieldAccessToken, accessToken)
+		req.URL.RawQuery = query.Encode()
+	}
 	// 判断参数是否为空
 	if param != nil {
 		var values url.Values
 		values, err = c.URLValues(param)
 		if err != nil {
-			return err
+			return
 		}
 		if method == http.MethodPost {
 			// 根据类型转换
@@ -340,31 +682,30 @@ func (c *Client) doRequest(method string, param Param, result interface{}) (err
 				if reqByte, err = json.Marshal(param); err != nil {
 					return
 				}
-				bodyBuffer := bytes.NewBuffer(reqByte)
-				req.Body = io.NopCloser(bodyBuffer)
-				req.ContentLength = int64(bodyBuffer.Len())
+				setRequestBody(req, reqByte)
 			} else {
 				var reqByte []byte
 				mapValues := c.formatUrlValueToMap(values)
 				if reqByte, err = xml.Marshal(payXml(mapValues)); err != nil {
 					return
 				}
-				req.Body = io.NopCloser(bytes.NewBuffer(reqByte))
+				setRequestBody(req, reqByte)
 			}
 		} else if method == http.MethodGet {
-			req.URL, _ = url.Parse(c.host + "?" + values.Encode())
+			query := req.URL.Query()
+			for k := range values {
+				query.Set(k, values.Get(k))
+			}
+			req.URL.RawQuery = query.Encode()
 		}
 	}
 	// 是否需要证书
+	httpClient = c.client
 	if param.NeedTlsCert() {
-		tlsConfig, err1 := c.LoadTlsCertConfig()
-		if err1 != nil {
-			err = err1
-			return
-		}
-		c.client.Transport = &http.Transport{
-			TLSClientConfig: tlsConfig,
+		if c.tlsClient == nil {
+			return nil, nil, ErrWxPemKeyNotFound
 		}
+		httpClient = c.tlsClient
 	}
 	// 添加header头
 	if param.ReturnType() == "jsonStr" || param.ReturnType() == "byte" {
@@ -372,32 +713,36 @@ func (c *Client) doRequest(method string, param Param, result interface{}) (err
 	} else {
 		req.Header.Set("Content-Type", kContentType)
 	}
-	// 发起请求数据
-	rsp, err := c.client.Do(req)
-	if err != nil {
-		return
-	}
-	defer rsp.Body.Close()
-	bodyBytes, err := io.ReadAll(rsp.Body)
-	if err != nil {
-		return fmt.Errorf("解析返回数据失败: %v", err)
-	}
-	err = c.decode(bodyBytes, method, param.ReturnType(), param.NeedVerify(), result)
 	return
 }
 
+// 设置请求内容，重试时通过 GetBody 重新读取
+func setRequestBody(req *http.Request, body []byte) {
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+}
+
 // 解密返回数据
-func (c *Client) decode(data []byte, method, returnType string, needVerifySign bool, result interface{}) (err error) {
+func (c *Client) decode(data []byte, method, returnType string, needVerifySign bool, signType string, result interface{}) (err error) {
 	// 返回结果
 	if c.onReceivedData != nil {
 		c.onReceivedData(method, data)
 	}
 	if strings.ToLower(returnType) == "json" || returnType == "jsonStr" || returnType == "byte" || returnType == "" {
 		if returnType == "byte" {
-			err = nil
+			// 失败时返回json格式的错误信息
+			if bytes.HasPrefix(data, []byte("{")) {
+				var aErr *AppletError
+				if json.Unmarshal(data, &aErr) == nil && aErr.IsFailure() {
+					return aErr
+				}
+			}
 			rsp := result.(*QrcodeRsp)
 			rsp.Buffer = data
-			return
+			return nil
 		}
 		var raw = make(map[string]json.RawMessage)
 		if err = json.Unmarshal(data, &raw); err != nil {
@@ -434,8 +779,8 @@ func (c *Client) decode(data []byte, method, returnType string, needVerifySign b
 				strValue := fmt.Sprintf("%v", value)
 				params.Add(key, strValue)
 			}
-			// 验证签名
-			if err = c.VerifySign(params); err != nil {
+			// 验证签名，返回数据没有 sign_type 时按接口指定的签名类型验证
+			if err = c.verifySign(params, signType); err != nil {
 				return
 			}
 		}
@@ -447,17 +792,27 @@ func (c *Client) decode(data []byte, method, returnType string, needVerifySign b
 	return
 }
 
-// 返回内容
-func (c *Client) OnReceivedData(fn func(method string, data []byte)) {
-	c.onReceivedData = fn
+// 验证签名，返回数据中带有 sign_type 时按该签名类型验证
+func (c *Client) VerifySign(values url.Values) (err error) {
+	return c.verifySign(values, "")
 }
 
-// 验证签名
-func (c *Client) VerifySign(values url.Values) (err error) {
+// 验证签名，返回数据中没有 sign_type 时使用 signType，为空时使用客户端的签名算法
+func (c *Client) verifySign(values url.Values, signType string) (err error) {
+	if v := values.Get(kFieldSignType); v != "" {
+		signType = v
+	}
+	signer := c.signer
+	if signType != "" && signType != signer.SignType() {
+		var ok bool
+		if signer, ok = signers[signType]; !ok {
+			return fmt.Errorf("wxpay: unsupported sign type %s", signType)
+		}
+	}
 	verifier := values.Get(kFieldSign)
-	compareSign := c.sign(values)
-	if strings.Compare(verifier, compareSign) != 0 {
-		err = fmt.Errorf("验证签名失败，接口返回签名：%s，生成签名：%s", compareSign, verifier)
+	compareSign := signer.Sign(c.formatBizQueryParaMap(values), c.mchSecret)
+	if !hmac.Equal([]byte(verifier), []byte(compareSign)) {
+		err = fmt.Errorf("验证签名失败，接口返回签名：%s，生成签名：%s", verifier, compareSign)
 		return
 	}
 	return
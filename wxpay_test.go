@@ -0,0 +1,473 @@
+package wxpay
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAppId     = "wx8888888888888888"
+	testSecret    = "test-applet-secret"
+	testMchId     = "1900000109"
+	testMchSecret = "192006250b4c09247ec02edce69f6a2d"
+)
+
+// 生成自签名证书，模拟商户API证书
+func newTestCert(t *testing.T) (pemCert, keyCert []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: testMchId},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemCert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyCert = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return
+}
+
+// 写入带签名的xml响应，签名类型与请求一致
+func writeTestPayXml(w http.ResponseWriter, c *Client, m map[string]string, signType ...string) {
+	values := make(url.Values)
+	for k, v := range m {
+		values.Set(k, v)
+	}
+	signer := c.signer
+	if len(signType) > 0 && signers[signType[0]] != nil {
+		signer = signers[signType[0]]
+	}
+	m[kFieldSign] = signer.Sign(c.formatBizQueryParaMap(values), c.mchSecret)
+	b, _ := xml.Marshal(payXml(m))
+	w.Write(b)
+}
+
+// 读取xml请求参数并验证签名
+func readTestPayXml(r *http.Request, c *Client) (map[string]string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string)
+	if err = xml.Unmarshal(body, (*payXml)(&m)); err != nil {
+		return nil, err
+	}
+	values := make(url.Values)
+	for k, v := range m {
+		values.Set(k, v)
+	}
+	return m, c.VerifySign(values)
+}
+
+// 模拟微信接口的本地服务，申请退款接口要求客户端证书
+func newTestServer(t *testing.T) (*httptest.Server, *Client) {
+	t.Helper()
+	signer, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sns/jscode2session", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get(kFieldAppId) != testAppId || q.Get(kFieldSecret) != testSecret {
+			json.NewEncoder(w).Encode(AppletError{Errcode: 40013, Errmsg: "invalid appid"})
+			return
+		}
+		json.NewEncoder(w).Encode(Code2SessionRsp{OpenId: "o-" + q.Get("js_code"), SessionKey: "session"})
+	})
+	mux.HandleFunc("/pay/unifiedorder", func(w http.ResponseWriter, r *http.Request) {
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			writeTestPayXml(w, signer, map[string]string{"return_code": "FAIL", "return_msg": err.Error()})
+			return
+		}
+		writeTestPayXml(w, signer, map[string]string{
+			"return_code": "SUCCESS",
+			"result_code": "SUCCESS",
+			"appid":       m[kFieldAppId],
+			"mch_id":      m[kFieldMchId],
+			"nonce_str":   signer.createNonceStr(),
+			"trade_type":  m["trade_type"],
+			"prepay_id":   "wx" + m["out_trade_no"],
+			"sub_appid":   m["sub_appid"],
+			"sub_mch_id":  m["sub_mch_id"],
+		}, m[kFieldSignType])
+	})
+	mux.HandleFunc("/secapi/pay/refund", func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			writeTestPayXml(w, signer, map[string]string{"return_code": "FAIL", "return_msg": "cert required"})
+			return
+		}
+		m, err := readTestPayXml(r, signer)
+		if err != nil {
+			writeTestPayXml(w, signer, map[string]string{"return_code": "FAIL", "return_msg": err.Error()})
+			return
+		}
+		writeTestPayXml(w, signer, map[string]string{
+			"return_code":   "SUCCESS",
+			"result_code":   "SUCCESS",
+			"appid":         m[kFieldAppId],
+			"mch_id":        m[kFieldMchId],
+			"nonce_str":     signer.createNonceStr(),
+			"out_trade_no":  m["out_trade_no"],
+			"out_refund_no": m["out_refund_no"],
+			"refund_id":     "50000" + m["out_refund_no"],
+			"refund_fee":    m["refund_fee"],
+			"total_fee":     m["total_fee"],
+			"sub_mch_id":    m["sub_mch_id"],
+		}, m[kFieldSignType])
+	})
+	server := httptest.NewUnstartedServer(mux)
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server, signer
+}
+
+func newTestClient(t *testing.T, server *httptest.Server, opts ...OptionFunc) *Client {
+	t.Helper()
+	opts = append([]OptionFunc{
+		WithApiHost(server.URL),
+		WithHttpClient(server.Client()),
+		WithMchInformation(testMchId, testMchSecret),
+	}, opts...)
+	c, err := New(testAppId, testSecret, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestNew_TlsCertFileNotFound(t *testing.T) {
+	_, err := New(testAppId, testSecret, WithTlsCertFile("not-exist.pem", "not-exist.key"))
+	if err == nil {
+		t.Fatal("expected error for missing cert file")
+	}
+}
+
+// 代理地址带路径前缀时拼接接口路径
+func TestClient_RequestUrlProxyPath(t *testing.T) {
+	c, err := New(testAppId, testSecret, WithApiHost("https://gw.example.com/wxpay/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := c.requestUrl(TradeOrderQuery{}); u != "https://gw.example.com/wxpay/pay/orderquery" {
+		t.Fatalf("unexpected url %s", u)
+	}
+	if u := c.requestUrl(GetPublicKey{}); u != "https://gw.example.com/wxpay/risk/getpublickey" {
+		t.Fatalf("unexpected url %s", u)
+	}
+}
+
+// 兼容旧用法，传入完整接口链接时不再拼接接口路径
+func TestClient_RequestUrlFullUrl(t *testing.T) {
+	c, err := New(testAppId, testSecret, WithApiHost("https://api.mch.weixin.qq.com/pay/unifiedorder"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := c.requestUrl(TradeApp{}); u != "https://api.mch.weixin.qq.com/pay/unifiedorder" {
+		t.Fatalf("unexpected url %s", u)
+	}
+	// 链接中已带上凭据时不再获取凭据
+	full := "https://api.weixin.qq.com/wxa/getwxacodeunlimit?access_token=token"
+	if c, err = New(testAppId, testSecret, WithApiHost(full)); err != nil {
+		t.Fatal(err)
+	}
+	if u := c.requestUrl(GetWxACodeUnLimit{}); u != full {
+		t.Fatalf("unexpected url %s", u)
+	}
+}
+
+func TestClient_TlsCertRequired(t *testing.T) {
+	server, _ := newTestServer(t)
+	c := newTestClient(t, server)
+	var p TradeRefund
+	p.OutTradeNo = "TEST2023112717521212345678"
+	p.OutRefundNo = "REFUND2023112717521212345678"
+	p.TotalFee = 1
+	p.RefundFee = 1
+	if _, err := c.TradeRefund(context.Background(), p); !errors.Is(err, ErrWxPemKeyNotFound) {
+		t.Fatalf("expected ErrWxPemKeyNotFound, got %v", err)
+	}
+}
+
+// 并发调用登录、支付与退款接口，配合 go test -race 检查数据竞争
+func TestClient_Concurrent(t *testing.T) {
+	server, _ := newTestServer(t)
+	pemCert, keyCert := newTestCert(t)
+	c := newTestClient(t, server, WithTlsCert(pemCert, keyCert))
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n*3)
+	for i := 0; i < n; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			var p Code2Session
+			p.JsCode = fmt.Sprintf("code%d", i)
+			r, err := c.Code2Session(context.Background(), p)
+			if err == nil && r.OpenId != "o-"+p.JsCode {
+				err = fmt.Errorf("code2session: unexpected openid %s", r.OpenId)
+			}
+			errs <- err
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			var p TradeApplet
+			p.Body = "支付测试"
+			p.OutTradeNo = fmt.Sprintf("TEST%028d", i)
+			p.TotalFee = 1
+			p.SpbillCreateIp = "127.0.0.1"
+			p.NotifyUrl = "https://www.weixin.qq.com/wxpay/pay.php"
+			p.OpenId = "openid"
+			r, err := c.TradeApplet(context.Background(), p)
+			if err == nil && r.Package != "prepay_id=wx"+p.OutTradeNo {
+				err = fmt.Errorf("applet: unexpected package %s", r.Package)
+			}
+			errs <- err
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			var p TradeRefund
+			p.OutTradeNo = fmt.Sprintf("TEST%028d", i)
+			p.OutRefundNo = fmt.Sprintf("REFUND%026d", i)
+			p.TotalFee = 1
+			p.RefundFee = 1
+			r, err := c.TradeRefund(context.Background(), p)
+			if err == nil && r.OutRefundNo != p.OutRefundNo {
+				err = fmt.Errorf("refund: unexpected out_refund_no %s", r.OutRefundNo)
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if http.DefaultClient.Transport != nil {
+		t.Fatal("http.DefaultClient.Transport must not be modified")
+	}
+}
+
+// 模拟响应缓慢的微信接口，直到请求被取消才返回
+func newSlowServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 读取完请求内容后服务端才能感知客户端断开
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_ContextTimeout(t *testing.T) {
+	server := newSlowServer(t)
+	c := newTestClient(t, server)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var p TradeOrderQuery
+	p.OutTradeNo = "TEST2023112717521212345678"
+	start := time.Now()
+	_, err := c.TradeOrderQuery(ctx, p)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("request was not aborted by the deadline")
+	}
+}
+
+func TestClient_ContextCancel(t *testing.T) {
+	server := newSlowServer(t)
+	c := newTestClient(t, server)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	var p Code2Session
+	p.JsCode = "code"
+	if _, err := c.Code2Session(ctx, p); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+// 微信支付签名文档示例 https://pay.weixin.qq.com/wiki/doc/api/jsapi.php?chapter=4_3
+func TestClient_Sign(t *testing.T) {
+	values := url.Values{}
+	values.Set("appid", "wxd930ea5d5a258f4f")
+	values.Set("mch_id", "10000100")
+	values.Set("device_info", "1000")
+	values.Set("body", "test")
+	values.Set("nonce_str", "ibuaiVcKdpRxkhJA")
+	tests := []struct {
+		signType string
+		want     string
+	}{
+		{SignTypeMD5, "9A0A8659F005D6984697E2CA0A9CF3B7"},
+		{SignTypeHMACSHA256, "6A9AE1657590FD6257D693A078E1C3E4BB6BA4DC30B23E0EE2496E54170DACD6"},
+	}
+	for _, tt := range tests {
+		c, err := New(testAppId, testSecret, WithMchInformation("10000100", testMchSecret), WithSignType(tt.signType))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.sign(values); got != tt.want {
+			t.Errorf("%s sign = %s, want %s", tt.signType, got, tt.want)
+		}
+	}
+}
+
+func TestClient_VerifySignType(t *testing.T) {
+	md5Client, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmacClient, err := New(testAppId, testSecret, WithMchInformation(testMchId, testMchSecret), WithSignType(SignTypeHMACSHA256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := url.Values{}
+	values.Set("out_trade_no", "TEST2023112717521212345678")
+	values.Set(kFieldSignType, SignTypeHMACSHA256)
+	values.Set(kFieldSign, hmacClient.sign(values))
+	// 按返回数据中的 sign_type 验证签名
+	if err = md5Client.VerifySign(values); err != nil {
+		t.Fatal(err)
+	}
+	values.Set("out_trade_no", "TEST2023112717521212345679")
+	if err = md5Client.VerifySign(values); err == nil {
+		t.Fatal("expected verify failure for tampered values")
+	}
+	if _, err = New(testAppId, testSecret, WithSignType("SHA1")); err == nil {
+		t.Fatal("expected error for unsupported sign type")
+	}
+}
+
+func TestClient_TradeAppletHMACSHA256(t *testing.T) {
+	server, _ := newTestServer(t)
+	c := newTestClient(t, server, WithSignType(SignTypeHMACSHA256))
+	var p TradeApplet
+	p.Body = "支付测试"
+	p.OutTradeNo = "TEST2023112717521212345678"
+	p.TotalFee = 1
+	p.SpbillCreateIp = "127.0.0.1"
+	p.NotifyUrl = "https://www.weixin.qq.com/wxpay/pay.php"
+	p.OpenId = "openid"
+	r, err := c.TradeApplet(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SignType != SignTypeHMACSHA256 {
+		t.Fatalf("signType = %s, want %s", r.SignType, SignTypeHMACSHA256)
+	}
+	values := url.Values{}
+	values.Set("appId", r.AppID)
+	values.Set("timeStamp", r.Timestamp)
+	values.Set("nonceStr", r.NonceStr)
+	values.Set("package", r.Package)
+	values.Set("signType", r.SignType)
+	values.Set(kFieldSign, r.PaySign)
+	if err = c.VerifySign(values); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_ServiceProvider(t *testing.T) {
+	server, _ := newTestServer(t)
+	pemCert, keyCert := newTestCert(t)
+	c := newTestClient(t, server, WithTlsCert(pemCert, keyCert))
+	sub := SubMerchant{SubAppId: "wxsubappid", SubMchId: "1900000109"}
+	var p TradeApplet
+	p.SubMerchant = sub
+	p.Body = "支付测试"
+	p.OutTradeNo = "TEST2023112717521212345678"
+	p.TotalFee = 1
+	p.SpbillCreateIp = "127.0.0.1"
+	p.NotifyUrl = "https://www.weixin.qq.com/wxpay/pay.php"
+	p.SubOpenId = "sub-openid"
+	r, err := c.TradeApplet(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 服务商模式下使用 sub_appid 调起支付并签名
+	if r.AppID != sub.SubAppId {
+		t.Fatalf("appId = %s, want %s", r.AppID, sub.SubAppId)
+	}
+	values := url.Values{}
+	values.Set("appId", sub.SubAppId)
+	values.Set("timeStamp", r.Timestamp)
+	values.Set("nonceStr", r.NonceStr)
+	values.Set("package", r.Package)
+	values.Set("signType", r.SignType)
+	values.Set(kFieldSign, r.PaySign)
+	if err = c.VerifySign(values); err != nil {
+		t.Fatal(err)
+	}
+
+	var refund TradeRefund
+	refund.SubMerchant = sub
+	refund.OutTradeNo = p.OutTradeNo
+	refund.OutRefundNo = "R" + p.OutTradeNo
+	refund.TotalFee = 1
+	refund.RefundFee = 1
+	rr, err := c.TradeRefund(context.Background(), refund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr.SubMchId != sub.SubMchId {
+		t.Fatalf("sub_mch_id = %s, want %s", rr.SubMchId, sub.SubMchId)
+	}
+
+	values, err = c.URLValues(TradeOrderQuery{SubMerchant: sub, OutTradeNo: p.OutTradeNo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values.Get(kFieldAppId) != testAppId || values.Get("sub_appid") != sub.SubAppId || values.Get("sub_mch_id") != sub.SubMchId {
+		t.Fatalf("unexpected values %v", values)
+	}
+
+	// sub_openid 需要同时传入 sub_appid，sub_appid 需要同时传入 sub_mch_id
+	p.SubMerchant = SubMerchant{}
+	if fields := invalidFields(t, p.Validate()); len(fields) != 1 || fields[0] != "sub_appid" {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+	p.SubMerchant = SubMerchant{SubAppId: sub.SubAppId}
+	if fields := invalidFields(t, p.Validate()); len(fields) != 1 || fields[0] != "sub_mch_id" {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+}
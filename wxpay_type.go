@@ -5,18 +5,33 @@ import "fmt"
 const (
 	kContentType     = "application/x-www-form-urlencoded;charset=utf-8"
 	kContentTypeJson = "application/json;charset=utf-8"
+	kContentTypeXml  = "application/xml;charset=utf-8"
 	kTimeFormat      = "2006-01-02 15:04:05"
+
+	kNotifyMaxBodySize = 1 << 20 // 通知内容最大长度
 )
 
 const (
-	kFieldAppId      = "appid"
-	kFieldSecret     = "secret"
-	kFieldMchId      = "mch_id"
-	kFieldNonceStr   = "nonce_str"
-	kFieldSign       = "sign"
-	kFieldSignType   = "sign_type"
-	kFieldErrCode    = "errcode"
-	kFieldReturnCode = "return_code"
+	SignTypeMD5        = "MD5"         // MD5签名
+	SignTypeHMACSHA256 = "HMAC-SHA256" // HMAC-SHA256签名
+)
+
+const (
+	kApiDomain = "https://api.weixin.qq.com"     // 小程序接口域名
+	kPayDomain = "https://api.mch.weixin.qq.com" // 微信支付接口域名
+)
+
+const (
+	kFieldAppId       = "appid"
+	kFieldSecret      = "secret"
+	kFieldMchId       = "mch_id"
+	kFieldNonceStr    = "nonce_str"
+	kFieldSign        = "sign"
+	kFieldSignType    = "sign_type"
+	kFieldErrCode     = "errcode"
+	kFieldReturnCode  = "return_code"
+	kFieldReqInfo     = "req_info"
+	kFieldAccessToken = "access_token"
 )
 
 type Param interface {
@@ -37,6 +52,31 @@ type Param interface {
 
 	// ReturnType 返回类型，v2版本的接口都是xml的，为兼容小程序接口需要切换json
 	ReturnType() string
+
+	// NeedAccessToken 是否需要接口调用凭据，有的接口需要，比如：小程序二维码接口、获取手机号接口，凭据会自动添加到请求链接
+	NeedAccessToken() bool
+
+	// ApiPath 接口路径，比如：/pay/orderquery，请求时与客户端配置的域名拼接，不在默认域名下的接口可返回完整链接
+	ApiPath() string
+
+	// Validate 校验请求参数，请求微信接口前调用，参数不合法时返回 ValidationErrors
+	Validate() error
+
+	// SignType 接口指定的签名类型，为空时使用客户端的签名类型，比如：下载资金账单接口只支持 HMAC-SHA256
+	SignType() string
+
+	// Fields 公共参数的字段名，不同接口的命名不同，比如：企业付款到零钱接口使用 mch_appid、mchid
+	Fields() ParamFields
+
+	// Idempotent 是否可以使用相同的请求内容安全重试，比如：查询订单、关闭订单、申请退款，统一下单等接口不会自动重试
+	Idempotent() bool
+}
+
+// ParamFields 公共参数字段名
+type ParamFields struct {
+	AppId    string // 公众账号ID，默认 appid
+	MchId    string // 商户号，默认 mch_id
+	SignType string // 签名类型，默认 sign_type，为空时不传签名类型
 }
 
 type AuxParam struct {
@@ -66,6 +106,30 @@ func (aux AuxParam) ReturnType() string {
 	return "json"
 }
 
+func (aux AuxParam) NeedAccessToken() bool {
+	return false
+}
+
+func (aux AuxParam) ApiPath() string {
+	return ""
+}
+
+func (aux AuxParam) Validate() error {
+	return nil
+}
+
+func (aux AuxParam) SignType() string {
+	return ""
+}
+
+func (aux AuxParam) Fields() ParamFields {
+	return ParamFields{AppId: kFieldAppId, MchId: kFieldMchId, SignType: kFieldSignType}
+}
+
+func (aux AuxParam) Idempotent() bool {
+	return false
+}
+
 // ReturnCode 微信支付接口响应错误码
 type ReturnCode string
 
@@ -88,8 +152,14 @@ func (c ErrCode) IsFailure() bool {
 	return c != ErrCodeSuccess
 }
 
+const (
+	ErrCodeInvalidAccessToken ErrCode = 40001 // 获取 access_token 时 AppSecret 错误，或者 access_token 无效
+	ErrCodeAccessTokenExpired ErrCode = 42001 // access_token 超时
+)
+
 const (
 	ReturnCodeSuccess ReturnCode = "SUCCESS" // 支付接口调用成功
+	ReturnCodeFail    ReturnCode = "FAIL"    // 支付接口调用失败
 	ErrCodeSuccess    ErrCode    = 0         // 小程序接口调用成功
 )
 
@@ -138,3 +208,8 @@ func (e AppletError) IsSuccess() bool {
 func (e AppletError) IsFailure() bool {
 	return e.Errcode.IsFailure()
 }
+
+// IsAccessTokenInvalid 接口调用凭据是否失效，失效后需要重新获取
+func (e AppletError) IsAccessTokenInvalid() bool {
+	return e.Errcode == ErrCodeInvalidAccessToken || e.Errcode == ErrCodeAccessTokenExpired
+}